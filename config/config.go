@@ -6,12 +6,21 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	AI       AIConfig       `mapstructure:"ai"`
-	Email    EmailConfig    `mapstructure:"email"`
-	Image    ImageConfig    `mapstructure:"image"`
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	AI           AIConfig           `mapstructure:"ai"`
+	Email        EmailConfig        `mapstructure:"email"`
+	Image        ImageConfig        `mapstructure:"image"`
+	Verification VerificationConfig `mapstructure:"verification"`
+	LoginGuard   LoginGuardConfig   `mapstructure:"login_guard"`
+	TwoFactor    TwoFactorConfig    `mapstructure:"two_factor"`
+	Handle       HandleConfig       `mapstructure:"handle"`
+	Account      AccountConfig      `mapstructure:"account"`
+	Tag          TagConfig          `mapstructure:"tag"`
+	Event        EventConfig        `mapstructure:"event"`
+	Story        StoryConfig        `mapstructure:"story"`
+	Moderation   ModerationConfig   `mapstructure:"moderation"`
 }
 
 type ServerConfig struct {
@@ -44,8 +53,9 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string        `mapstructure:"secret"`
-	Expire time.Duration `mapstructure:"expire"`
+	Secret        string        `mapstructure:"secret"`
+	Expire        time.Duration `mapstructure:"expire"`
+	RefreshExpire time.Duration `mapstructure:"refresh_expire"`
 }
 
 type AIConfig struct {
@@ -65,8 +75,63 @@ type EmailConfig struct {
 }
 
 type ImageConfig struct {
-	UploadDir string `mapstructure:"upload_dir"`
-	URLPrefix string `mapstructure:"url_prefix"`
+	UploadDir     string `mapstructure:"upload_dir"`
+	URLPrefix     string `mapstructure:"url_prefix"`
+	ThumbnailSize int    `mapstructure:"thumbnail_size"` // 动态图片缩略图长边像素
+}
+
+type VerificationConfig struct {
+	Store         string        `mapstructure:"store"` // memory 或 database
+	CodeTTL       time.Duration `mapstructure:"code_ttl"`
+	EmailCooldown time.Duration `mapstructure:"email_cooldown"`
+	IPCooldown    time.Duration `mapstructure:"ip_cooldown"`
+	MaxAttempts   int           `mapstructure:"max_attempts"`
+	SweepInterval time.Duration `mapstructure:"sweep_interval"`
+}
+
+type LoginGuardConfig struct {
+	MaxFailures    int           `mapstructure:"max_failures"`    // 同一邮箱连续失败多少次后开始锁定
+	IPMaxFailures  int           `mapstructure:"ip_max_failures"` // 同一IP连续失败多少次后开始锁定
+	BaseLockout    time.Duration `mapstructure:"base_lockout"`    // 首次锁定时长，之后每次失败翻倍
+	MaxLockout     time.Duration `mapstructure:"max_lockout"`
+	ResetAfter     time.Duration `mapstructure:"reset_after"`     // 超过该时长没有失败则计数清零
+	AlertThreshold int           `mapstructure:"alert_threshold"` // 失败达到该次数时邮件提醒账号所有者，0为关闭
+}
+
+type TwoFactorConfig struct {
+	Issuer          string        `mapstructure:"issuer"`           // 验证器App中显示的名称
+	ChallengeExpire time.Duration `mapstructure:"challenge_expire"` // 登录二次验证的有效期
+}
+
+type HandleConfig struct {
+	ChangeCooldown time.Duration `mapstructure:"change_cooldown"` // 两次修改用户ID的最小间隔
+	Reserved       []string      `mapstructure:"reserved"`        // 额外的保留ID
+}
+
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `mapstructure:"deletion_grace_period"` // 申请注销后的冷静期，期间登录即撤销注销
+	PurgeInterval       time.Duration `mapstructure:"purge_interval"`        // 检查到期注销账号的间隔
+}
+
+type TagConfig struct {
+	TrendingWindow time.Duration `mapstructure:"trending_window"` // 热门话题默认统计的时间窗口
+	TrendingLimit  int           `mapstructure:"trending_limit"`  // 热门话题默认返回条数
+}
+
+type EventConfig struct {
+	LikeCoalesceWindow time.Duration `mapstructure:"like_coalesce_window"` // 同一用户对同一动态反复点赞的合并窗口
+}
+
+type StoryConfig struct {
+	TTL           time.Duration `mapstructure:"ttl"`            // 快拍的有效期
+	SweepInterval time.Duration `mapstructure:"sweep_interval"` // 清理过期快拍的间隔
+}
+
+type ModerationConfig struct {
+	DictFile       string            `mapstructure:"dict_file"`       // 敏感词词库文件，[分类] 开始一个分类，之后每行一个词
+	ReloadInterval time.Duration     `mapstructure:"reload_interval"` // 检查词库文件是否修改的间隔
+	DefaultAction  string            `mapstructure:"default_action"`  // 未单独配置的分类的处理方式
+	Actions        map[string]string `mapstructure:"actions"`         // 分类 -> block（拒绝）、mask（替换为***）或 flag（放行并记录待审核）
 }
 
 var GlobalConfig Config
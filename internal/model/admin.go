@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// 审计日志中的管理操作
+const (
+	AuditActionDismissReport  = "dismiss_report"
+	AuditActionDeleteContent  = "delete_content"
+	AuditActionWarnUser       = "warn_user"
+	AuditActionSuspendUser    = "suspend_user"
+	AuditActionLiftSuspension = "lift_suspension"
+)
+
+// UserWarning 管理员对用户的警告
+type UserWarning struct {
+	WarningID int64     `gorm:"column:warning_id;primary_key;auto_increment" json:"warning_id"`
+	UID       string    `gorm:"column:uid" json:"uid"`
+	AdminUID  string    `gorm:"column:admin_uid" json:"admin_uid"`
+	ReportID  *int64    `gorm:"column:report_id" json:"report_id"`
+	Reason    string    `gorm:"column:reason" json:"reason"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// UserSuspension 封禁记录，ExpiresAt 为空表示永久封禁，提前解封时记录 LiftedAt；只读封禁仍可登录，但不能发布内容和聊天
+type UserSuspension struct {
+	SuspensionID int64      `gorm:"column:suspension_id;primary_key;auto_increment" json:"suspension_id"`
+	UID          string     `gorm:"column:uid" json:"uid"`
+	AdminUID     string     `gorm:"column:admin_uid" json:"admin_uid"`
+	ReportID     *int64     `gorm:"column:report_id" json:"report_id"`
+	Reason       string     `gorm:"column:reason" json:"reason"`
+	ReadOnly     bool       `gorm:"column:read_only" json:"read_only"`
+	ExpiresAt    *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	LiftedAt     *time.Time `gorm:"column:lifted_at" json:"lifted_at"`
+	LiftedBy     *string    `gorm:"column:lifted_by" json:"lifted_by"`
+	CreatedAt    time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+// AuditLog 管理员操作记录，只增不改
+type AuditLog struct {
+	LogID      int64     `gorm:"column:log_id;primary_key;auto_increment" json:"log_id"`
+	AdminUID   string    `gorm:"column:admin_uid" json:"admin_uid"`
+	Action     string    `gorm:"column:action" json:"action"`
+	TargetType string    `gorm:"column:target_type" json:"target_type"`
+	TargetID   string    `gorm:"column:target_id" json:"target_id"`
+	ReportID   *int64    `gorm:"column:report_id" json:"report_id"`
+	Detail     string    `gorm:"column:detail" json:"detail"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (UserWarning) TableName() string {
+	return "user_warnings"
+}
+
+func (UserSuspension) TableName() string {
+	return "user_suspensions"
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
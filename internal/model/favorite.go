@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// 收藏的来源
+const (
+	FavoriteSourcePost           = "post"
+	FavoriteSourcePrivateMessage = "private_message"
+	FavoriteSourceGroupMessage   = "group_message"
+)
+
+// FavoriteFolder 收藏夹分组
+type FavoriteFolder struct {
+	FolderID  int64     `gorm:"column:folder_id;primary_key;auto_increment" json:"folder_id"`
+	UID       string    `gorm:"column:uid" json:"-"`
+	Name      string    `gorm:"column:name" json:"name"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// Favorite 收藏，收藏时保存内容快照，原内容删除或撤回后仍可查看
+type Favorite struct {
+	FavoriteID      int64     `gorm:"column:favorite_id;primary_key;auto_increment" json:"favorite_id"`
+	UID             string    `gorm:"column:uid" json:"-"`
+	FolderID        *int64    `gorm:"column:folder_id" json:"folder_id"`
+	SourceType      string    `gorm:"column:source_type" json:"source_type"`
+	SourceID        int64     `gorm:"column:source_id" json:"source_id"`
+	Title           string    `gorm:"column:title" json:"title"`
+	Content         string    `gorm:"column:content" json:"content"`
+	Extra           string    `gorm:"column:extra" json:"extra"` // 快照附加信息（图片、消息类型等），JSON
+	AuthorUID       string    `gorm:"column:author_uid" json:"author_uid"`
+	AuthorName      string    `gorm:"column:author_name" json:"author_name"`
+	Note            string    `gorm:"column:note" json:"note"`
+	SourceCreatedAt time.Time `gorm:"column:source_created_at" json:"source_created_at"`
+	CreatedAt       time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (FavoriteFolder) TableName() string {
+	return "favorite_folders"
+}
+
+func (Favorite) TableName() string {
+	return "favorites"
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// LoginHistory 登录记录表，成功和失败的尝试都会记录
+type LoginHistory struct {
+	ID        int64     `gorm:"column:id;primary_key;auto_increment" json:"id"`
+	UID       *string   `gorm:"column:uid" json:"-"` // 邮箱未注册时为空
+	Email     string    `gorm:"column:email" json:"email"`
+	IP        string    `gorm:"column:ip" json:"ip"`
+	UserAgent string    `gorm:"column:user_agent" json:"user_agent"`
+	Success   bool      `gorm:"column:success" json:"success"`
+	Reason    string    `gorm:"column:reason" json:"reason"` // 失败原因：wrong_password, locked 等
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (LoginHistory) TableName() string {
+	return "login_history"
+}
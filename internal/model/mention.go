@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// 提及的来源
+const (
+	MentionSourcePost         = "post"
+	MentionSourceComment      = "comment"
+	MentionSourceGroupMessage = "group_message"
+)
+
+// Mention 一条内容中对某个用户的@，同一来源对同一用户只记录一次
+type Mention struct {
+	ID           int64     `gorm:"column:id;primary_key;auto_increment" json:"id"`
+	SourceType   string    `gorm:"column:source_type" json:"source_type"`
+	SourceID     int64     `gorm:"column:source_id" json:"source_id"`
+	MentionedUID string    `gorm:"column:mentioned_uid" json:"mentioned_uid"`
+	FromUID      string    `gorm:"column:from_uid" json:"from_uid"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (Mention) TableName() string {
+	return "mentions"
+}
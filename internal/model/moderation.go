@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// 内容审核的场景
+const (
+	ModerationScenePost           = "post"
+	ModerationSceneComment        = "comment"
+	ModerationScenePrivateMessage = "private_message"
+	ModerationSceneGroupMessage   = "group_message"
+	ModerationSceneGroupName      = "group_name"
+	ModerationSceneUserName       = "user_name"
+	ModerationSceneSignature      = "signature"
+	ModerationSceneStory          = "story"
+)
+
+// 待审核记录的状态
+const (
+	ModerationFlagPending  = "pending"
+	ModerationFlagApproved = "approved" // 审核后确认无问题
+	ModerationFlagRejected = "rejected" // 审核后确认违规
+)
+
+// ModerationFlag 命中 flag 类敏感词的内容，已正常发布，等待人工审核
+type ModerationFlag struct {
+	FlagID    int64     `gorm:"column:flag_id;primary_key;auto_increment" json:"flag_id"`
+	Scene     string    `gorm:"column:scene" json:"scene"`
+	SourceID  string    `gorm:"column:source_id" json:"source_id"` // 动态、评论、消息的ID，资料类为用户uid
+	UID       string    `gorm:"column:uid" json:"uid"`
+	Content   string    `gorm:"column:content" json:"content"` // 发布时的内容快照
+	Hits      string    `gorm:"column:hits" json:"hits"`       // 命中的词和分类，JSON数组
+	Status    string    `gorm:"column:status" json:"status"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (ModerationFlag) TableName() string {
+	return "moderation_flags"
+}
@@ -1,17 +1,52 @@
 package model
 
-import "time"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 type Post struct {
-	PostID    int64     `gorm:"column:post_id;primary_key;auto_increment" json:"post_id"`
-	UserID    string    `gorm:"column:user_id" json:"user_id"`
-	Title     string    `gorm:"column:title" json:"title"`
-	Content   string    `gorm:"column:content" json:"content"`
-	ImageURL  string    `gorm:"column:image_url" json:"image_url"`
-	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
-	Likes     []PostLike  `gorm:"foreignKey:PostID" json:"likes"`
-	Comments  []Comment   `gorm:"foreignKey:PostID" json:"comments"`
+	PostID     int64          `gorm:"column:post_id;primary_key;auto_increment" json:"post_id"`
+	UserID     string         `gorm:"column:user_id" json:"user_id"`
+	Title      string         `gorm:"column:title" json:"title"`
+	Content    string         `gorm:"column:content" json:"content"`
+	ImageURL   string         `gorm:"column:image_url" json:"image_url"`
+	Visibility string         `gorm:"column:visibility;default:public" json:"visibility"` // public, friends, private, custom
+	RepostOfID *int64         `gorm:"column:repost_of_id" json:"repost_of_id"`            // 转发的原动态，原创动态为空
+	ShareCount int            `gorm:"column:share_count" json:"share_count"`              // 被转发和分享到聊天的次数
+	CreatedAt  time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"column:updated_at" json:"updated_at"`
+	EditedAt   *time.Time     `gorm:"column:edited_at" json:"edited_at"` // 作者最近一次编辑的时间，未编辑过为空
+	DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
+	Likes      []PostLike     `gorm:"foreignKey:PostID" json:"likes"`
+	Comments   []Comment      `gorm:"foreignKey:PostID" json:"comments"`
+	Images     []PostImage    `gorm:"foreignKey:PostID" json:"images"`
+}
+
+// 单条动态的可见范围
+const (
+	VisibilityPublic  = "public"
+	VisibilityFriends = "friends"
+	VisibilityPrivate = "private"
+	VisibilityCustom  = "custom" // 仅 post_visible_users 中指定的用户可见
+)
+
+// PostVisibleUser 自定义可见范围的动态允许查看的用户
+type PostVisibleUser struct {
+	PostID int64  `gorm:"column:post_id;primaryKey" json:"post_id"`
+	UserID string `gorm:"column:user_id;primaryKey" json:"user_id"`
+}
+
+// PostImage 动态图片，每条动态最多9张，按Position排序
+type PostImage struct {
+	ID           int64     `gorm:"column:id;primary_key;auto_increment" json:"id"`
+	PostID       int64     `gorm:"column:post_id" json:"post_id"`
+	Position     int       `gorm:"column:position" json:"position"`
+	URL          string    `gorm:"column:url" json:"url"`
+	ThumbnailURL string    `gorm:"column:thumbnail_url" json:"thumbnail_url"`
+	Width        int       `gorm:"column:width" json:"width"`
+	Height       int       `gorm:"column:height" json:"height"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
 }
 
 type PostLike struct {
@@ -21,33 +56,78 @@ type PostLike struct {
 }
 
 type Comment struct {
-	CommentID int64     `gorm:"column:comment_id;primary_key;auto_increment" json:"comment_id"`
-	PostID    int64     `gorm:"column:post_id" json:"post_id"`
-	UserID    string    `gorm:"column:user_id" json:"user_id"`
-	Content   string    `gorm:"column:content" json:"content"`
-	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	CommentID       int64          `gorm:"column:comment_id;primary_key;auto_increment" json:"comment_id"`
+	PostID          int64          `gorm:"column:post_id" json:"post_id"`
+	UserID          string         `gorm:"column:user_id" json:"user_id"`
+	Content         string         `gorm:"column:content" json:"content"`
+	ParentCommentID *int64         `gorm:"column:parent_comment_id" json:"parent_comment_id"` // 所属的一级评论，一级评论为空
+	ReplyToUserID   *string        `gorm:"column:reply_to_user_id" json:"reply_to_user_id"`   // 回复的对象
+	LikeCount       int            `gorm:"column:like_count" json:"like_count"`
+	ReplyCount      int            `gorm:"column:reply_count" json:"reply_count"` // 一级评论下未删除的回复数
+	CreatedAt       time.Time      `gorm:"column:created_at" json:"created_at"`
+	EditedAt        *time.Time     `gorm:"column:edited_at" json:"edited_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
+}
+
+type CommentLike struct {
+	CommentID int64     `gorm:"column:comment_id;primaryKey" json:"comment_id"`
+	UserID    string    `gorm:"column:user_id;primaryKey" json:"user_id"`
+	LikedAt   time.Time `gorm:"column:liked_at" json:"liked_at"`
 }
 
 type PostPreview struct {
-	PostID      int64   `json:"post_id"`
-	Title       string  `json:"title"`
-	UserID      string  `json:"user_id"`
-	UserName    string  `json:"user_name"`
-	UserAvatar  *string `json:"user_avatar"`
-	FirstImage  *string `json:"first_image"`
-	LikesCount  int64   `json:"likes_count"`
-	IsLiked     bool    `json:"is_liked"`
-	CreatedAt   string  `json:"created_at"`
+	PostID        int64     `json:"post_id"`
+	Title         string    `json:"title"`
+	UserID        string    `json:"user_id"`
+	UserName      string    `json:"user_name"`
+	UserAvatar    *string   `json:"user_avatar"`
+	FirstImage    *string   `json:"first_image"`
+	LikesCount    int64     `json:"likes_count"`
+	CommentsCount int64     `json:"comments_count"`
+	IsLiked       bool      `json:"is_liked"`
+	ShareCount    int       `json:"share_count"`
+	RepostOf      *PostCard `json:"repost_of,omitempty"` // 转发动态的原动态卡片
+	CreatedAt     string    `json:"created_at"`
+}
+
+// 动态卡片状态
+const (
+	PostCardAvailable   = "available"
+	PostCardDeleted     = "deleted"     // 原动态已删除
+	PostCardUnavailable = "unavailable" // 当前用户无权查看
+)
+
+// PostCard 转发和聊天分享中展示的动态卡片，每次按原动态的最新内容生成
+type PostCard struct {
+	PostID     int64  `json:"post_id"`
+	Status     string `json:"status"`
+	Title      string `json:"title,omitempty"`
+	Content    string `json:"content,omitempty"` // 内容摘要
+	FirstImage string `json:"first_image,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	UserName   string `json:"user_name,omitempty"`
 }
 
 func (Post) TableName() string {
 	return "posts"
 }
 
+func (PostVisibleUser) TableName() string {
+	return "post_visible_users"
+}
+
+func (PostImage) TableName() string {
+	return "post_images"
+}
+
 func (PostLike) TableName() string {
 	return "post_likes"
 }
 
 func (Comment) TableName() string {
 	return "comments"
+}
+
+func (CommentLike) TableName() string {
+	return "comment_likes"
 } 
\ No newline at end of file
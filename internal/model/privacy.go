@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// 搜索可见范围
+const (
+	SearchPolicyAll    = "all"    // 可通过用户ID和昵称模糊搜索到
+	SearchPolicyHandle = "handle" // 只能通过完整用户ID搜索到
+	SearchPolicyNone   = "none"   // 不出现在搜索结果中
+)
+
+// 好友申请处理方式
+const (
+	FriendPolicyApproval   = "approval"    // 需要验证
+	FriendPolicyAutoAccept = "auto_accept" // 自动通过
+	FriendPolicyDisabled   = "disabled"    // 不允许添加
+)
+
+// 动态可见范围
+const (
+	PostVisibilityEveryone = "everyone"
+	PostVisibilityFriends  = "friends"
+	PostVisibilityNobody   = "nobody"
+)
+
+// UserPrivacy 用户隐私设置，没有记录时使用 DefaultPrivacy
+type UserPrivacy struct {
+	UID              string    `gorm:"column:uid;primary_key" json:"-"`
+	SearchPolicy     string    `gorm:"column:search_policy" json:"search_policy"`
+	FriendPolicy     string    `gorm:"column:friend_policy" json:"friend_policy"`
+	PostVisibility   string    `gorm:"column:post_visibility" json:"post_visibility"`
+	ShowOnlineStatus bool      `gorm:"column:show_online_status" json:"show_online_status"`
+	UpdatedAt        time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// DefaultPrivacy 返回默认隐私设置：所有人可搜索、添加好友需验证、动态所有人可见、显示在线状态
+func DefaultPrivacy(uid string) UserPrivacy {
+	return UserPrivacy{
+		UID:              uid,
+		SearchPolicy:     SearchPolicyAll,
+		FriendPolicy:     FriendPolicyApproval,
+		PostVisibility:   PostVisibilityEveryone,
+		ShowOnlineStatus: true,
+	}
+}
+
+func (UserPrivacy) TableName() string {
+	return "user_privacy"
+}
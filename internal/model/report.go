@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// 举报对象的类型
+const (
+	ReportTargetPost           = "post"
+	ReportTargetComment        = "comment"
+	ReportTargetPrivateMessage = "private_message"
+	ReportTargetGroupMessage   = "group_message"
+	ReportTargetUser           = "user"
+	ReportTargetGroup          = "group"
+)
+
+// 举报的处理状态
+const (
+	ReportStatusPending   = "pending"
+	ReportStatusResolved  = "resolved"  // 已处理：删除内容、警告或封禁
+	ReportStatusDismissed = "dismissed" // 驳回
+)
+
+// Report 用户举报，提交时保存被举报内容的快照，内容删除后仍可查看
+type Report struct {
+	ReportID    int64      `gorm:"column:report_id;primary_key;auto_increment" json:"report_id"`
+	ReporterUID string     `gorm:"column:reporter_uid" json:"reporter_uid"`
+	TargetType  string     `gorm:"column:target_type" json:"target_type"`
+	TargetID    string     `gorm:"column:target_id" json:"target_id"`   // 动态、评论、消息的ID，用户为uid，群为gid
+	TargetUID   string     `gorm:"column:target_uid" json:"target_uid"` // 内容作者，举报群时为群主
+	Reason      string     `gorm:"column:reason" json:"reason"`
+	Detail      string     `gorm:"column:detail" json:"detail"`
+	Content     string     `gorm:"column:content" json:"content"`
+	Status      string     `gorm:"column:status" json:"status"`
+	Action      string     `gorm:"column:action" json:"action"` // 管理员采取的处理方式
+	HandlerUID  *string    `gorm:"column:handler_uid" json:"handler_uid"`
+	HandleNote  string     `gorm:"column:handle_note" json:"handle_note"`
+	HandledAt   *time.Time `gorm:"column:handled_at" json:"handled_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+func (Report) TableName() string {
+	return "reports"
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Session 登录会话表，每次登录生成一条记录，refresh token 与之绑定
+type Session struct {
+	SessionID       string     `gorm:"column:session_id;primary_key" json:"session_id"`
+	UID             string     `gorm:"column:uid" json:"uid"`
+	RefreshHash     string     `gorm:"column:refresh_hash" json:"-"`      // 当前refresh token的哈希
+	PrevRefreshHash string     `gorm:"column:prev_refresh_hash" json:"-"` // 上一个refresh token的哈希，用于检测重放
+	UserAgent       string     `gorm:"column:user_agent" json:"user_agent"`
+	IP              string     `gorm:"column:ip" json:"ip"`
+	CreatedAt       time.Time  `gorm:"column:created_at" json:"created_at"`
+	LastUsedAt      time.Time  `gorm:"column:last_used_at" json:"last_used_at"`
+	ExpiresAt       time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	RevokedAt       *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+}
+
+func (Session) TableName() string {
+	return "user_sessions"
+}
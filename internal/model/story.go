@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// 快拍类型
+const (
+	StoryTypeImage = "image"
+	StoryTypeText  = "text"
+)
+
+// Story 快拍，只对好友可见，过期后由后台任务删除
+type Story struct {
+	StoryID   int64     `gorm:"column:story_id;primary_key;auto_increment" json:"story_id"`
+	UserID    string    `gorm:"column:user_id" json:"user_id"`
+	Type      string    `gorm:"column:type" json:"type"`
+	Content   string    `gorm:"column:content" json:"content"` // 文字快拍的内容或图片快拍的配文
+	ImageURL  string    `gorm:"column:image_url" json:"image_url"`
+	ExpiresAt time.Time `gorm:"column:expires_at" json:"expires_at"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// StoryView 快拍的浏览记录，每人只记录第一次浏览
+type StoryView struct {
+	StoryID   int64     `gorm:"column:story_id;primaryKey" json:"story_id"`
+	ViewerUID string    `gorm:"column:viewer_uid;primaryKey" json:"viewer_uid"`
+	ViewedAt  time.Time `gorm:"column:viewed_at" json:"viewed_at"`
+}
+
+func (Story) TableName() string {
+	return "stories"
+}
+
+func (StoryView) TableName() string {
+	return "story_views"
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Tag 话题，名称统一转为小写保存
+type Tag struct {
+	TagID     int64     `gorm:"column:tag_id;primary_key;auto_increment" json:"tag_id"`
+	Name      string    `gorm:"column:name" json:"name"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// PostTag 动态与话题的关联，CreatedAt 冗余动态的发布时间，用于统计热门话题
+type PostTag struct {
+	PostID    int64     `gorm:"column:post_id;primaryKey" json:"post_id"`
+	TagID     int64     `gorm:"column:tag_id;primaryKey" json:"tag_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+func (PostTag) TableName() string {
+	return "post_tags"
+}
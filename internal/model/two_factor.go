@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// UserTOTP 用户的TOTP两步验证配置，Enabled为false表示已生成密钥但尚未确认
+type UserTOTP struct {
+	UID          string     `gorm:"column:uid;primary_key" json:"uid"`
+	Secret       string     `gorm:"column:secret" json:"-"`
+	Enabled      bool       `gorm:"column:enabled" json:"enabled"`
+	LastUsedStep int64      `gorm:"column:last_used_step" json:"-"` // 最近一次使用的时间步，防止验证码重放
+	CreatedAt    time.Time  `gorm:"column:created_at" json:"created_at"`
+	EnabledAt    *time.Time `gorm:"column:enabled_at" json:"enabled_at"`
+}
+
+// RecoveryCode 两步验证的一次性恢复码
+type RecoveryCode struct {
+	ID        int64      `gorm:"column:id;primary_key;auto_increment" json:"id"`
+	UID       string     `gorm:"column:uid" json:"uid"`
+	CodeHash  string     `gorm:"column:code_hash" json:"-"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+}
+
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
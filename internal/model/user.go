@@ -4,17 +4,26 @@ import (
 	"time"
 )
 
+// 用户角色
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 type User struct {
-	UID        string    `gorm:"column:uid;primary_key" json:"uid"`
-	ID         string    `gorm:"column:id;unique" json:"id"`
-	Email      string    `gorm:"column:email;unique" json:"email"`
-	Name       string    `gorm:"column:name" json:"name"`
-	Password   string    `gorm:"column:password" json:"-"`
-	AvatarURL  string    `gorm:"column:avatar_url" json:"avatar_url"`
-	Signature  string    `gorm:"column:signature" json:"signature"`
-	Status     int       `gorm:"column:status" json:"status"`
-	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt  time.Time `gorm:"column:updated_at" json:"updated_at"`
+	UID         string     `gorm:"column:uid;primary_key" json:"uid"`
+	ID          string     `gorm:"column:id;unique" json:"id"`
+	Email       string     `gorm:"column:email;unique" json:"email"`
+	Name        string     `gorm:"column:name" json:"name"`
+	Password    string     `gorm:"column:password" json:"-"`
+	AvatarURL   string     `gorm:"column:avatar_url" json:"avatar_url"`
+	Signature   string     `gorm:"column:signature" json:"signature"`
+	Status      int        `gorm:"column:status" json:"status"`
+	IDChangedAt *time.Time `gorm:"column:id_changed_at" json:"id_changed_at"` // 最近一次修改用户ID的时间
+	DeleteAt    *time.Time `gorm:"column:delete_at" json:"delete_at"`         // 申请注销后计划彻底删除的时间，为空表示正常账号
+	Role        string     `gorm:"column:role;default:user" json:"role"`      // user 或 admin，管理员需在数据库中手动设置
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"updated_at"`
 }
 
 type Friend struct {
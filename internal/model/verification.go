@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// VerificationCode 邮箱验证码表（数据库存储方式下使用）
+type VerificationCode struct {
+	ID        int64      `gorm:"column:id;primary_key;auto_increment" json:"id"`
+	Purpose   string     `gorm:"column:purpose" json:"purpose"`
+	Email     string     `gorm:"column:email" json:"email"`
+	CodeHash  string     `gorm:"column:code_hash" json:"-"`
+	IP        string     `gorm:"column:ip" json:"ip"`
+	Attempts  int        `gorm:"column:attempts" json:"attempts"` // 已校验失败次数
+	ExpiresAt time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+}
+
+func (VerificationCode) TableName() string {
+	return "verification_codes"
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type accountSendCodeRequest struct {
+	Purpose string `json:"purpose" binding:"required"` // change_email 或 delete_account
+	Email   string `json:"email"`                      // change_email 时为新邮箱
+}
+
+type resetPasswordRequest struct {
+	Email      string `json:"email" binding:"required,email"`
+	VarifyCode string `json:"varifycode" binding:"required"`
+	NewPasswd  string `json:"new_passwd" binding:"required"`
+}
+
+type changeEmailRequest struct {
+	NewEmail   string `json:"new_email" binding:"required,email"`
+	VarifyCode string `json:"varifycode" binding:"required"`
+	Passwd     string `json:"passwd" binding:"required"`
+}
+
+// accountSendCodeHandler 已登录用户申请更换邮箱、注销账号的验证码
+func accountSendCodeHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req accountSendCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	var email string
+	switch req.Purpose {
+	case codePurposeChangeEmail:
+		// 验证码发往新邮箱，以确认新邮箱归属
+		email = req.Email
+		if email == "" || email == user.Email {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请输入新的邮箱地址"})
+			return
+		}
+		var count int64
+		db.Model(&model.User{}).Where("email = ?", email).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱已存在"})
+			return
+		}
+	case codePurposeDeleteAccount:
+		email = user.Email
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的验证码用途"})
+		return
+	}
+
+	if err := sendVerificationCode(req.Purpose, email, c.ClientIP()); err != nil {
+		if err == ErrCodeCooldown {
+			respondCodeError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "邮件发送失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "验证码已发送"})
+}
+
+// resetPasswordHandler 通过邮箱验证码重置密码，成功后所有设备需要重新登录
+func (s *HTTPServer) resetPasswordHandler(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := verifyCode(codePurposeResetPassword, req.Email, req.VarifyCode); err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱未注册"})
+		return
+	}
+
+	if err := db.Model(&user).Updates(map[string]interface{}{
+		"password":   hashPassword(req.NewPasswd),
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置密码失败"})
+		return
+	}
+
+	if err := revokeUserSessions(db, user.UID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "注销登录状态失败"})
+		return
+	}
+	s.ws.DisconnectUser(user.UID, "密码已重置，请重新登录")
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，请重新登录"})
+}
+
+// changeEmailHandler 更换绑定邮箱，需要当前密码和新邮箱收到的验证码
+func (s *HTTPServer) changeEmailHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req changeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ? AND password = ?", userID, hashPassword(req.Passwd)).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密码错误"})
+		return
+	}
+
+	if err := verifyCode(codePurposeChangeEmail, req.NewEmail, req.VarifyCode); err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	var count int64
+	db.Model(&model.User{}).Where("email = ?", req.NewEmail).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱已存在"})
+		return
+	}
+
+	if err := db.Model(&user).Updates(map[string]interface{}{
+		"email":      req.NewEmail,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更换邮箱失败"})
+		return
+	}
+
+	if err := revokeUserSessions(db, user.UID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "注销登录状态失败"})
+		return
+	}
+	s.ws.DisconnectUser(user.UID, "绑定邮箱已更换，请重新登录")
+
+	c.JSON(http.StatusOK, gin.H{"message": "邮箱已更换，请重新登录", "email": req.NewEmail})
+}
@@ -0,0 +1,478 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const defaultDeletionGracePeriod = 7 * 24 * time.Hour
+
+type deleteAccountRequest struct {
+	VarifyCode string `json:"varifycode" binding:"required"`
+	Passwd     string `json:"passwd" binding:"required"`
+}
+
+// deletionGracePeriod 注销冷静期，未配置时使用默认值，避免申请后立即被彻底删除
+func deletionGracePeriod() time.Duration {
+	if period := config.GlobalConfig.Account.DeletionGracePeriod; period > 0 {
+		return period
+	}
+	return defaultDeletionGracePeriod
+}
+
+// deleteAccountHandler 申请注销账号，进入冷静期，到期后由后台任务彻底删除
+func (s *HTTPServer) deleteAccountHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ? AND password = ?", userID, hashPassword(req.Passwd)).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密码错误"})
+		return
+	}
+
+	if err := verifyCode(codePurposeDeleteAccount, user.Email, req.VarifyCode); err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	deleteAt := time.Now().Add(deletionGracePeriod())
+	if err := db.Model(&user).Updates(map[string]interface{}{
+		"delete_at":  deleteAt,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "申请注销失败"})
+		return
+	}
+
+	if err := revokeUserSessions(db, user.UID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "注销登录状态失败"})
+		return
+	}
+	s.ws.DisconnectUser(user.UID, "账号已申请注销")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   fmt.Sprintf("账号将于%s彻底删除，在此之前重新登录即可撤销注销", deleteAt.Format("2006-01-02 15:04:05")),
+		"delete_at": deleteAt.Format("2006-01-02 15:04:05"),
+	})
+}
+
+// cancelAccountDeletion 冷静期内登录时撤销注销申请，返回是否撤销了注销
+func cancelAccountDeletion(db *gorm.DB, user *model.User) bool {
+	if user.DeleteAt == nil {
+		return false
+	}
+	if err := db.Model(&model.User{}).Where("uid = ?", user.UID).Update("delete_at", nil).Error; err != nil {
+		log.Printf("撤销账号注销失败: %v", err)
+		return false
+	}
+	user.DeleteAt = nil
+	return true
+}
+
+// startAccountPurger 定期删除冷静期已过的账号
+func startAccountPurger(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purgeExpiredAccounts()
+		}
+	}()
+}
+
+func purgeExpiredAccounts() {
+	db, err := getDB()
+	if err != nil {
+		log.Printf("清理注销账号失败: %v", err)
+		return
+	}
+
+	var uids []string
+	if err := db.Model(&model.User{}).Where("delete_at IS NOT NULL AND delete_at <= ?", time.Now()).
+		Pluck("uid", &uids).Error; err != nil {
+		log.Printf("查询待注销账号失败: %v", err)
+		return
+	}
+
+	for _, uid := range uids {
+		if err := purgeAccount(db, uid); err != nil {
+			log.Printf("删除账号 %s 失败: %v", uid, err)
+		}
+	}
+}
+
+// purgeAccount 彻底删除账号及其数据，自己创建的群转让给其他成员，无人可转让时解散
+func purgeAccount(db *gorm.DB, uid string) error {
+	var imageURLs []string
+	var storyImageURLs []string
+	var postImages []model.PostImage
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := transferOwnedGroups(tx, uid); err != nil {
+			return err
+		}
+
+		// 动态：先删除自己动态下所有人的评论和点赞，再删除自己在别人动态下的互动
+		var postIDs []int64
+		if err := tx.Unscoped().Model(&model.Post{}).Where("user_id = ?", uid).Pluck("post_id", &postIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Post{}).Where("user_id = ? AND image_url != ''", uid).Pluck("image_url", &imageURLs).Error; err != nil {
+			return err
+		}
+		if len(postIDs) > 0 {
+			if err := tx.Where("post_id IN ?", postIDs).Find(&postImages).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN ?", postIDs).Delete(&model.PostImage{}).Error; err != nil {
+				return err
+			}
+			var commentIDs []int64
+			if err := tx.Unscoped().Model(&model.Comment{}).Where("post_id IN ?", postIDs).Pluck("comment_id", &commentIDs).Error; err != nil {
+				return err
+			}
+			if err := deleteMentions(tx, model.MentionSourceComment, commentIDs); err != nil {
+				return err
+			}
+			if len(commentIDs) > 0 {
+				if err := tx.Where("comment_id IN ?", commentIDs).Delete(&model.CommentLike{}).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Unscoped().Where("post_id IN ?", postIDs).Delete(&model.Comment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN ?", postIDs).Delete(&model.PostLike{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN ?", postIDs).Delete(&model.PostVisibleUser{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN ?", postIDs).Delete(&model.PostTag{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("user_id = ?", uid).Delete(&model.Post{}).Error; err != nil {
+			return err
+		}
+		if err := releaseUserCommentCounters(tx, uid); err != nil {
+			return err
+		}
+		// 别人动态下还有回复的一级评论保留为匿名占位，否则这些回复会从评论列表中消失
+		if err := tx.Unscoped().Model(&model.Comment{}).
+			Where("user_id = ? AND parent_comment_id IS NULL AND reply_count > 0", uid).
+			Updates(map[string]interface{}{
+				"user_id":    "",
+				"content":    "",
+				"like_count": 0,
+				"edited_at":  nil,
+				"deleted_at": gorm.Expr("COALESCE(deleted_at, ?)", time.Now()),
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", uid).Delete(&model.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&model.PostLike{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&model.PostVisibleUser{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("mentioned_uid = ? OR from_uid = ?", uid, uid).Delete(&model.Mention{}).Error; err != nil {
+			return err
+		}
+
+		// 快拍：自己的快拍连同浏览记录，以及自己浏览别人快拍的记录
+		if err := tx.Model(&model.Story{}).Where("user_id = ? AND image_url != ''", uid).Pluck("image_url", &storyImageURLs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("story_id IN (?) OR viewer_uid = ?",
+			tx.Model(&model.Story{}).Select("story_id").Where("user_id = ?", uid), uid).
+			Delete(&model.StoryView{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&model.Story{}).Error; err != nil {
+			return err
+		}
+
+		// 消息：删除私聊双方的记录以及自己发送的群消息
+		if err := tx.Where("sender_id = ? OR receiver_id = ?", uid, uid).Delete(&model.PrivateMessage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("sender_id = ?", uid).Delete(&model.GroupMessage{}).Error; err != nil {
+			return err
+		}
+
+		// AI对话
+		if err := tx.Where("conversation_id IN (?)",
+			tx.Model(&model.AIConversation{}).Select("conversation_id").Where("user_id = ?", uid)).
+			Delete(&model.AIMessage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&model.AIConversation{}).Error; err != nil {
+			return err
+		}
+
+		// 好友关系、群成员、申请记录
+		if err := tx.Where("user_id = ? OR friend_id = ?", uid, uid).Delete(&model.Friend{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("from_uid = ? OR to_uid = ?", uid, uid).Delete(&model.FriendRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&model.GroupJoinRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.GroupJoinRequest{}).Where("handler_uid = ?", uid).Update("handler_uid", nil).Error; err != nil {
+			return err
+		}
+
+		// 账号安全相关数据；登录记录保留，但解除与账号的关联
+		if err := tx.Where("uid = ?", uid).Delete(&model.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.UserTOTP{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.UserPrivacy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.Favorite{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.FavoriteFolder{}).Error; err != nil {
+			return err
+		}
+		// 自己提交的举报以及自己收到的警告、封禁记录；管理员操作记录保留
+		if err := tx.Where("reporter_uid = ?", uid).Delete(&model.Report{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.UserWarning{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&model.UserSuspension{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.LoginHistory{}).Where("uid = ?", uid).
+			Updates(map[string]interface{}{"uid": nil, "email": ""}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("uid = ?", uid).Delete(&model.User{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, imageURL := range imageURLs {
+		if err := os.Remove(filepath.Join(postUploadDir, path.Base(imageURL))); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除动态图片失败: %v", err)
+		}
+	}
+	removePostImageFiles(postImages)
+	removeStoryFiles(storyImageURLs)
+	return nil
+}
+
+// releaseUserCommentCounters 删除用户的回复和评论点赞前，扣减对应评论上的计数，并清除别人对用户评论的点赞
+func releaseUserCommentCounters(tx *gorm.DB, uid string) error {
+	type parentCount struct {
+		ParentCommentID int64 `gorm:"column:parent_comment_id"`
+		Total           int   `gorm:"column:total"`
+	}
+	var counts []parentCount
+	if err := tx.Model(&model.Comment{}).
+		Select("parent_comment_id, COUNT(*) AS total").
+		Where("user_id = ? AND parent_comment_id IS NOT NULL", uid).
+		Group("parent_comment_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+	for _, pc := range counts {
+		if err := tx.Model(&model.Comment{}).Unscoped().Where("comment_id = ?", pc.ParentCommentID).
+			Update("reply_count", gorm.Expr("GREATEST(reply_count - ?, 0)", pc.Total)).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&model.Comment{}).Unscoped().
+		Where("comment_id IN (?)", tx.Model(&model.CommentLike{}).Select("comment_id").Where("user_id = ?", uid)).
+		Update("like_count", gorm.Expr("GREATEST(like_count - 1, 0)")).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? OR comment_id IN (?)", uid,
+		tx.Unscoped().Model(&model.Comment{}).Select("comment_id").Where("user_id = ?", uid)).
+		Delete(&model.CommentLike{}).Error
+}
+
+// transferOwnedGroups 将用户创建的群转让给管理员或最早入群的成员
+func transferOwnedGroups(tx *gorm.DB, uid string) error {
+	var groups []model.ChatGroup
+	if err := tx.Where("owner_id = ?", uid).Find(&groups).Error; err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		var heir model.GroupMember
+		err := tx.Where("gid = ? AND uid != ?", group.GID, uid).
+			Order("role = 'admin' DESC, joined_at ASC").
+			First(&heir).Error
+		if err == gorm.ErrRecordNotFound {
+			// 群里只剩自己，直接解散
+			if err := tx.Where("group_id = ?", strconv.Itoa(group.GID)).Delete(&model.GroupMessage{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("gid = ?", group.GID).Delete(&model.GroupMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("group_id = ?", group.GID).Delete(&model.GroupJoinRequest{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&group).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.ChatGroup{}).Where("gid = ?", group.GID).Update("owner_id", heir.UID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.GroupMember{}).Where("gid = ? AND uid = ?", group.GID, heir.UID).Update("role", "owner").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportAccountHandler 导出个人数据，打包为ZIP，内含JSON文件
+func exportAccountHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	var friends []model.Friend
+	var groups []model.GroupMember
+	var privateMessages []model.PrivateMessage
+	var groupMessages []model.GroupMessage
+	var posts []model.Post
+	var comments []model.Comment
+	var likes []model.PostLike
+	var conversations []model.AIConversation
+	var aiMessages []model.AIMessage
+	var favoriteFolders []model.FavoriteFolder
+	var favorites []model.Favorite
+
+	queries := []*gorm.DB{
+		db.Where("user_id = ?", userID).Find(&friends),
+		db.Where("uid = ?", userID).Find(&groups),
+		db.Where("sender_id = ? OR receiver_id = ?", userID, userID).Order("timestamp").Find(&privateMessages),
+		db.Where("sender_id = ?", userID).Order("timestamp").Find(&groupMessages),
+		db.Where("user_id = ?", userID).Order("created_at").Find(&posts),
+		db.Where("user_id = ?", userID).Order("created_at").Find(&comments),
+		db.Where("user_id = ?", userID).Order("liked_at").Find(&likes),
+		db.Where("user_id = ?", userID).Order("created_at").Find(&conversations),
+		db.Where("conversation_id IN (?)",
+			db.Model(&model.AIConversation{}).Select("conversation_id").Where("user_id = ?", userID)).
+			Order("created_at").Find(&aiMessages),
+		db.Where("uid = ?", userID).Order("created_at").Find(&favoriteFolders),
+		db.Where("uid = ?", userID).Order("created_at").Find(&favorites),
+	}
+	for _, q := range queries {
+		if q.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导出数据失败"})
+			return
+		}
+	}
+
+	profile := buildProfileResponse(&user)
+	profile.Email = user.Email
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"friends.json", friends},
+		{"groups.json", groups},
+		{"messages/private.json", privateMessages},
+		{"messages/group.json", groupMessages},
+		{"posts/posts.json", posts},
+		{"posts/comments.json", comments},
+		{"posts/likes.json", likes},
+		{"ai/conversations.json", conversations},
+		{"ai/messages.json", aiMessages},
+		{"favorites/folders.json", favoriteFolders},
+		{"favorites/favorites.json", favorites},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导出数据失败"})
+			return
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导出数据失败"})
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出数据失败"})
+		return
+	}
+
+	filename := fmt.Sprintf("netherlink-%s-%s.zip", user.ID, time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
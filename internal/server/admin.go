@@ -0,0 +1,465 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 管理员处理举报的方式
+const (
+	reportActionDeleteContent = "delete_content"
+	reportActionWarn          = "warn"
+	reportActionSuspend       = "suspend"
+	reportActionDismiss       = "dismiss"
+
+	maxHandleNoteLength = 200
+)
+
+var errReportActionUnsupported = errors.New("该类型的举报不支持删除内容")
+
+// 支持删除内容的举报类型
+var deletableReportTargets = map[string]bool{
+	model.ReportTargetPost:           true,
+	model.ReportTargetComment:        true,
+	model.ReportTargetPrivateMessage: true,
+	model.ReportTargetGroupMessage:   true,
+}
+
+type handleReportRequest struct {
+	Action   string `json:"action" binding:"required"`
+	Note     string `json:"note"`
+	Duration string `json:"duration"`  // 封禁时长，如 72h，为空表示永久封禁
+	ReadOnly bool   `json:"read_only"` // 只读封禁
+}
+
+// AdminReportItem 举报队列中的一条举报
+type AdminReportItem struct {
+	model.Report
+	ReporterName string `json:"reporter_name"`
+	TargetName   string `json:"target_name"`
+	PendingCount int64  `json:"pending_count"` // 同一对象待处理的举报数
+}
+
+// AccountWarningNotification 警告推送结构
+type AccountWarningNotification struct {
+	WarningID int64  `json:"warning_id"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"created_at"`
+}
+
+// adminMiddleware 只允许管理员访问，需放在 authMiddleware 之后
+func adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db, err := getDB()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+			c.Abort()
+			return
+		}
+
+		var user model.User
+		if err := db.Select("uid, role").Where("uid = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+			c.Abort()
+			return
+		}
+		if user.Role != model.UserRoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// writeAuditLog 记录管理员操作
+func writeAuditLog(tx *gorm.DB, adminUID, action, targetType, targetID string, reportID *int64, detail map[string]interface{}) error {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.AuditLog{
+		AdminUID:   adminUID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ReportID:   reportID,
+		Detail:     string(data),
+		CreatedAt:  time.Now(),
+	}).Error
+}
+
+// getAdminReportsHandler 管理员分页浏览举报，待处理的按提交时间从早到晚排列
+func getAdminReportsHandler(c *gin.Context) {
+	page, pageSize := parsePage(c)
+	status := c.DefaultQuery("status", model.ReportStatusPending)
+	if status != model.ReportStatusPending && status != model.ReportStatusResolved && status != model.ReportStatusDismissed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的举报状态"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	query := db.Model(&model.Report{}).Where("status = ?", status)
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取举报失败"})
+		return
+	}
+
+	order := "created_at ASC"
+	if status != model.ReportStatusPending {
+		order = "handled_at DESC"
+	}
+	var reports []model.Report
+	if err := query.Session(&gorm.Session{}).Order(order).
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取举报失败"})
+		return
+	}
+
+	items, err := buildAdminReportItems(db, reports)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取举报失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"reports": items,
+		"total":   total,
+		"page":    page,
+	})
+}
+
+// buildAdminReportItems 补充举报人、被举报人的昵称以及同一对象的待处理举报数
+func buildAdminReportItems(db *gorm.DB, reports []model.Report) ([]AdminReportItem, error) {
+	items := make([]AdminReportItem, 0, len(reports))
+	if len(reports) == 0 {
+		return items, nil
+	}
+
+	var uids []string
+	for _, report := range reports {
+		uids = append(uids, report.ReporterUID)
+		if report.TargetUID != "" {
+			uids = append(uids, report.TargetUID)
+		}
+	}
+	var users []model.User
+	if err := db.Select("uid, name").Where("uid IN ?", uids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, user := range users {
+		names[user.UID] = user.Name
+	}
+
+	type targetCount struct {
+		TargetType string `gorm:"column:target_type"`
+		TargetID   string `gorm:"column:target_id"`
+		Total      int64  `gorm:"column:total"`
+	}
+	var counts []targetCount
+	conditions := make([]string, 0, len(reports))
+	args := make([]interface{}, 0, len(reports)*2)
+	for _, report := range reports {
+		conditions = append(conditions, "(target_type = ? AND target_id = ?)")
+		args = append(args, report.TargetType, report.TargetID)
+	}
+	if err := db.Model(&model.Report{}).
+		Select("target_type, target_id, COUNT(*) AS total").
+		Where("status = ?", model.ReportStatusPending).
+		Where(strings.Join(conditions, " OR "), args...).
+		Group("target_type, target_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	pending := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		pending[count.TargetType+":"+count.TargetID] = count.Total
+	}
+
+	for _, report := range reports {
+		items = append(items, AdminReportItem{
+			Report:       report,
+			ReporterName: names[report.ReporterUID],
+			TargetName:   names[report.TargetUID],
+			PendingCount: pending[report.TargetType+":"+report.TargetID],
+		})
+	}
+	return items, nil
+}
+
+// removeReportedContent 在事务内删除被举报的动态、评论或消息，内容已不存在时返回false；
+// 返回的函数用于在事务提交后删除图片文件
+func removeReportedContent(tx *gorm.DB, report *model.Report) (bool, func(), error) {
+	switch report.TargetType {
+	case model.ReportTargetPost:
+		var post model.Post
+		if err := tx.Where("post_id = ?", report.TargetID).First(&post).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return false, nil, nil
+			}
+			return false, nil, err
+		}
+		images, err := deletePostRecords(tx, &post)
+		if err != nil {
+			return false, nil, err
+		}
+		return true, func() { removePostFiles(&post, images) }, nil
+
+	case model.ReportTargetComment:
+		var comment model.Comment
+		if err := tx.Where("comment_id = ?", report.TargetID).First(&comment).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return false, nil, nil
+			}
+			return false, nil, err
+		}
+		return true, nil, removeComment(tx, &comment)
+
+	case model.ReportTargetPrivateMessage:
+		result := tx.Where("id = ?", report.TargetID).Delete(&model.PrivateMessage{})
+		return result.RowsAffected > 0, nil, result.Error
+
+	case model.ReportTargetGroupMessage:
+		id, err := strconv.ParseInt(report.TargetID, 10, 64)
+		if err != nil {
+			return false, nil, nil
+		}
+		if err := deleteMentions(tx, model.MentionSourceGroupMessage, []int64{id}); err != nil {
+			return false, nil, err
+		}
+		result := tx.Where("id = ?", id).Delete(&model.GroupMessage{})
+		return result.RowsAffected > 0, nil, result.Error
+
+	default:
+		return false, nil, errReportActionUnsupported
+	}
+}
+
+// handleReportHandler 管理员处理举报：删除内容、警告、封禁或驳回，同一对象的其他待处理举报一并结案
+func (s *HTTPServer) handleReportHandler(c *gin.Context) {
+	adminUID := c.GetString("user_id")
+
+	reportID, err := strconv.ParseInt(c.Param("report_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的举报ID"})
+		return
+	}
+
+	var req handleReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(req.Note) > maxHandleNoteLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "处理说明不能超过200个字符"})
+		return
+	}
+
+	var expiresAt *time.Time
+	switch req.Action {
+	case reportActionDeleteContent, reportActionWarn, reportActionDismiss:
+	case reportActionSuspend:
+		if expiresAt, err = parseSuspendDuration(req.Duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的处理方式"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var report model.Report
+	if err := db.First(&report, reportID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "举报不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询举报失败"})
+		}
+		return
+	}
+	if report.Status != model.ReportStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "该举报已处理"})
+		return
+	}
+	if (req.Action == reportActionWarn || req.Action == reportActionSuspend) && report.TargetUID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "被举报的用户已不存在"})
+		return
+	}
+	if req.Action == reportActionSuspend {
+		var target model.User
+		if err := db.Select("uid, role").Where("uid = ?", report.TargetUID).First(&target).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "被举报的用户已不存在"})
+			return
+		}
+		if target.Role == model.UserRoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "不能封禁管理员"})
+			return
+		}
+	}
+
+	// 警告和封禁的原因默认使用举报原因
+	reason := req.Note
+	if reason == "" {
+		reason = "违反社区规范：" + report.Reason
+	}
+	detail := map[string]interface{}{"target_uid": report.TargetUID, "note": req.Note}
+
+	// 不支持删除内容的举报类型提前拒绝，避免进入事务
+	if req.Action == reportActionDeleteContent && !deletableReportTargets[report.TargetType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errReportActionUnsupported.Error()})
+		return
+	}
+
+	var warning *model.UserWarning
+	var suspension *model.UserSuspension
+	var removeFiles func()
+	now := time.Now()
+	status := model.ReportStatusResolved
+	if req.Action == reportActionDismiss {
+		status = model.ReportStatusDismissed
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var auditAction string
+		switch req.Action {
+		case reportActionDismiss:
+			auditAction = model.AuditActionDismissReport
+		case reportActionDeleteContent:
+			auditAction = model.AuditActionDeleteContent
+			found, cleanup, err := removeReportedContent(tx, &report)
+			if err != nil {
+				return err
+			}
+			removeFiles = cleanup
+			detail["content_found"] = found
+		case reportActionWarn:
+			auditAction = model.AuditActionWarnUser
+			warning = &model.UserWarning{
+				UID:       report.TargetUID,
+				AdminUID:  adminUID,
+				ReportID:  &report.ReportID,
+				Reason:    reason,
+				CreatedAt: now,
+			}
+			if err := tx.Create(warning).Error; err != nil {
+				return err
+			}
+			detail["warning_id"] = warning.WarningID
+		case reportActionSuspend:
+			auditAction = model.AuditActionSuspendUser
+			var err error
+			suspension, err = createSuspension(tx, report.TargetUID, adminUID, reason, req.ReadOnly, expiresAt, &report.ReportID)
+			if err != nil {
+				return err
+			}
+			detail["suspension_id"] = suspension.SuspensionID
+			detail["read_only"] = req.ReadOnly
+			detail["expires_at"] = formatOptionalTime(expiresAt)
+		}
+
+		if err := tx.Model(&model.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, model.ReportStatusPending).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"action":      req.Action,
+				"handler_uid": adminUID,
+				"handle_note": req.Note,
+				"handled_at":  now,
+			}).Error; err != nil {
+			return err
+		}
+		return writeAuditLog(tx, adminUID, auditAction, report.TargetType, report.TargetID, &report.ReportID, detail)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理举报失败"})
+		return
+	}
+	if removeFiles != nil {
+		removeFiles()
+	}
+
+	if warning != nil {
+		s.events.Publish([]string{warning.UID}, "account_warning", AccountWarningNotification{
+			WarningID: warning.WarningID,
+			Reason:    warning.Reason,
+			CreatedAt: warning.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	if suspension != nil {
+		s.ws.NotifySuspended(suspension.UID, suspension)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report_id": report.ReportID, "status": status, "action": req.Action})
+}
+
+// getAuditLogsHandler 管理员分页查看操作记录，可按管理员、操作和对象筛选
+func getAuditLogsHandler(c *gin.Context) {
+	page, pageSize := parsePage(c)
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	query := db.Model(&model.AuditLog{})
+	if adminUID := c.Query("admin_uid"); adminUID != "" {
+		query = query.Where("admin_uid = ?", adminUID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取操作记录失败"})
+		return
+	}
+
+	var logs []model.AuditLog
+	if err := query.Session(&gorm.Session{}).Order("created_at DESC, log_id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取操作记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":  logs,
+		"total": total,
+		"page":  page,
+	})
+}
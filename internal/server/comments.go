@@ -0,0 +1,337 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	commentSortTime = "time" // 按时间倒序
+	commentSortHot  = "hot"  // 按点赞数、回复数倒序
+
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 50
+	// 一级评论下预览的回复条数
+	replyPreviewSize = 3
+)
+
+// CommentResponse 评论响应结构，一级评论带回复预览
+type CommentResponse struct {
+	CommentID       int64             `json:"comment_id"`
+	PostID          int64             `json:"post_id"`
+	UserID          string            `json:"user_id"`
+	UserName        string            `json:"user_name"`
+	UserAvatar      string            `json:"user_avatar"`
+	Content         string            `json:"content"`
+	ParentCommentID *int64            `json:"parent_comment_id"`
+	ReplyToUserID   *string           `json:"reply_to_user_id"`
+	ReplyToUserName string            `json:"reply_to_user_name,omitempty"`
+	LikeCount       int               `json:"like_count"`
+	ReplyCount      int               `json:"reply_count"`
+	IsLiked         bool              `json:"is_liked"`
+	Deleted         bool              `json:"deleted"` // 已删除但仍有回复的一级评论，仅作占位展示
+	CreatedAt       string            `json:"created_at"`
+	EditedAt        interface{}       `json:"edited_at"`
+	Replies         []CommentResponse `json:"replies,omitempty"`
+}
+
+// parsePage 读取 page、page_size 分页参数，评论和搜索共用
+func parsePage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultCommentPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxCommentPageSize {
+		pageSize = defaultCommentPageSize
+	}
+	return page, pageSize
+}
+
+// buildCommentResponses 批量补充评论者、被回复者信息和点赞状态
+func buildCommentResponses(db *gorm.DB, viewerUID string, comments []model.Comment) ([]CommentResponse, error) {
+	result := make([]CommentResponse, 0, len(comments))
+	if len(comments) == 0 {
+		return result, nil
+	}
+
+	var uids []string
+	var commentIDs []int64
+	for _, comment := range comments {
+		uids = append(uids, comment.UserID)
+		if comment.ReplyToUserID != nil {
+			uids = append(uids, *comment.ReplyToUserID)
+		}
+		commentIDs = append(commentIDs, comment.CommentID)
+	}
+
+	var users []model.User
+	if err := db.Where("uid IN ?", uids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	userMap := make(map[string]model.User, len(users))
+	for _, user := range users {
+		userMap[user.UID] = user
+	}
+
+	var likedIDs []int64
+	if err := db.Model(&model.CommentLike{}).
+		Where("comment_id IN ? AND user_id = ?", commentIDs, viewerUID).
+		Pluck("comment_id", &likedIDs).Error; err != nil {
+		return nil, err
+	}
+	liked := make(map[int64]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	for _, comment := range comments {
+		resp := CommentResponse{
+			CommentID:       comment.CommentID,
+			PostID:          comment.PostID,
+			UserID:          comment.UserID,
+			Content:         comment.Content,
+			ParentCommentID: comment.ParentCommentID,
+			ReplyToUserID:   comment.ReplyToUserID,
+			LikeCount:       comment.LikeCount,
+			ReplyCount:      comment.ReplyCount,
+			IsLiked:         liked[comment.CommentID],
+			CreatedAt:       comment.CreatedAt.Format("2006-01-02 15:04:05"),
+			EditedAt:        formatOptionalTime(comment.EditedAt),
+		}
+		if user, ok := userMap[comment.UserID]; ok {
+			resp.UserName = user.Name
+			resp.UserAvatar = user.AvatarURL
+		} else {
+			resp.UserName = "未知用户"
+		}
+		if comment.ReplyToUserID != nil {
+			resp.ReplyToUserName = userMap[*comment.ReplyToUserID].Name
+		}
+		if comment.DeletedAt.Valid {
+			resp.Deleted = true
+			resp.UserID = ""
+			resp.UserName = ""
+			resp.UserAvatar = ""
+			resp.Content = "该评论已删除"
+			resp.EditedAt = nil
+		}
+		result = append(result, resp)
+	}
+	return result, nil
+}
+
+// listRootComments 分页查询一级评论，并附带每条评论最早的几条回复
+func listRootComments(db *gorm.DB, viewerUID string, postID int64, sort string, page, pageSize int) ([]CommentResponse, int64, error) {
+	// 已删除的一级评论如果还有回复，保留占位
+	query := db.Unscoped().Model(&model.Comment{}).
+		Where("post_id = ? AND parent_comment_id IS NULL", postID).
+		Where("deleted_at IS NULL OR reply_count > 0")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if sort == commentSortHot {
+		query = query.Order("like_count DESC, reply_count DESC, created_at DESC")
+	} else {
+		query = query.Order("created_at DESC, comment_id DESC")
+	}
+
+	var roots []model.Comment
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&roots).Error; err != nil {
+		return nil, 0, err
+	}
+
+	result, err := buildCommentResponses(db, viewerUID, roots)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range result {
+		if roots[i].ReplyCount == 0 {
+			continue
+		}
+		var replies []model.Comment
+		if err := db.Where("parent_comment_id = ?", roots[i].CommentID).
+			Order("created_at ASC, comment_id ASC").
+			Limit(replyPreviewSize).
+			Find(&replies).Error; err != nil {
+			return nil, 0, err
+		}
+		if result[i].Replies, err = buildCommentResponses(db, viewerUID, replies); err != nil {
+			return nil, 0, err
+		}
+	}
+	return result, total, nil
+}
+
+// loadVisiblePost 查询帖子并检查查看权限，失败时已写入响应
+func loadVisiblePost(c *gin.Context, db *gorm.DB, userID string) (*model.Post, bool) {
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的帖子ID"})
+		return nil, false
+	}
+
+	var post model.Post
+	if err := db.First(&post, postID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "帖子不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询帖子失败"})
+		}
+		return nil, false
+	}
+	if visible, err := canViewPost(db, userID, &post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询帖子失败"})
+		return nil, false
+	} else if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"error": errPostsHidden.Error()})
+		return nil, false
+	}
+	return &post, true
+}
+
+// getCommentsHandler 分页获取帖子的一级评论，sort=time 按时间，sort=hot 按热度
+func getCommentsHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	post, ok := loadVisiblePost(c, db, userID)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	comments, total, err := listRootComments(db, userID, post.PostID, c.DefaultQuery("sort", commentSortTime), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments": comments,
+		"total":    total,
+		"page":     page,
+	})
+}
+
+// getCommentRepliesHandler 分页获取一级评论下的全部回复，按时间正序
+func getCommentRepliesHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	post, ok := loadVisiblePost(c, db, userID)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.ParseInt(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	query := db.Model(&model.Comment{}).Where("post_id = ? AND parent_comment_id = ?", post.PostID, commentID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回复失败"})
+		return
+	}
+
+	var replies []model.Comment
+	if err := query.Order("created_at ASC, comment_id ASC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回复失败"})
+		return
+	}
+
+	result, err := buildCommentResponses(db, userID, replies)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回复失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"replies": result,
+		"total":   total,
+		"page":    page,
+	})
+}
+
+// toggleCommentLikeHandler 点赞/取消点赞评论
+func toggleCommentLikeHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	if _, ok := loadVisiblePost(c, db, userID); !ok {
+		return
+	}
+	comment, ok := loadComment(c, db)
+	if !ok {
+		return
+	}
+
+	var liked bool
+	var likeCount int
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("comment_id = ? AND user_id = ?", comment.CommentID, userID).Delete(&model.CommentLike{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		delta := -1
+		if result.RowsAffected == 0 {
+			// 原本未点赞，创建点赞记录
+			if err := tx.Create(&model.CommentLike{
+				CommentID: comment.CommentID,
+				UserID:    userID,
+				LikedAt:   time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+			delta = 1
+			liked = true
+		}
+
+		if err := tx.Model(&model.Comment{}).Where("comment_id = ?", comment.CommentID).
+			Update("like_count", gorm.Expr("GREATEST(like_count + ?, 0)", delta)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Comment{}).Where("comment_id = ?", comment.CommentID).
+			Pluck("like_count", &likeCount).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comment_id": comment.CommentID,
+		"is_liked":   liked,
+		"like_count": likeCount,
+	})
+}
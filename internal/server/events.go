@@ -0,0 +1,121 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"sync"
+	"time"
+)
+
+const defaultLikeCoalesceWindow = 5 * time.Second
+
+// PostLikedEvent 动态被点赞时推送给作者的 post_liked 事件
+type PostLikedEvent struct {
+	PostID     int64  `json:"post_id"`
+	PostTitle  string `json:"post_title"`
+	FromUID    string `json:"from_uid"`
+	FromName   string `json:"from_name"`
+	FromAvatar string `json:"from_avatar"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// PostCommentedEvent 动态被评论时推送给作者的 post_commented 事件
+type PostCommentedEvent struct {
+	PostID          int64  `json:"post_id"`
+	PostTitle       string `json:"post_title"`
+	CommentID       int64  `json:"comment_id"`
+	ParentCommentID *int64 `json:"parent_comment_id"`
+	FromUID         string `json:"from_uid"`
+	FromName        string `json:"from_name"`
+	FromAvatar      string `json:"from_avatar"`
+	Content         string `json:"content"` // 评论摘要
+	CreatedAt       string `json:"created_at"`
+}
+
+// likeKey 同一用户对同一动态的点赞
+type likeKey struct {
+	postID  int64
+	fromUID string
+}
+
+// pendingLike 合并窗口内的点赞状态，窗口结束时仍为点赞才推送
+type pendingLike struct {
+	timer     *time.Timer
+	liked     bool
+	authorUID string
+	event     PostLikedEvent
+}
+
+// EventDispatcher HTTP接口产生的实时事件统一经此推送给在线用户
+type EventDispatcher struct {
+	ws             *WSServer
+	coalesceWindow time.Duration
+
+	mu    sync.Mutex
+	likes map[likeKey]*pendingLike
+}
+
+func newEventDispatcher(ws *WSServer, cfg config.EventConfig) *EventDispatcher {
+	window := cfg.LikeCoalesceWindow
+	if window <= 0 {
+		window = defaultLikeCoalesceWindow
+	}
+	return &EventDispatcher{
+		ws:             ws,
+		coalesceWindow: window,
+		likes:          make(map[likeKey]*pendingLike),
+	}
+}
+
+// Publish 立即推送事件
+func (d *EventDispatcher) Publish(uids []string, eventType string, payload interface{}) {
+	if len(uids) == 0 {
+		return
+	}
+	d.ws.PushEvent(uids, eventType, payload)
+}
+
+// PostLikeToggled 记录点赞状态变化，窗口内的多次切换合并为一次，最终为取消点赞则不推送
+func (d *EventDispatcher) PostLikeToggled(authorUID string, liked bool, event PostLikedEvent) {
+	if authorUID == event.FromUID {
+		return
+	}
+	key := likeKey{postID: event.PostID, fromUID: event.FromUID}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if pending, ok := d.likes[key]; ok {
+		pending.liked = liked
+		if liked {
+			pending.event = event
+		}
+		pending.timer.Reset(d.coalesceWindow)
+		return
+	}
+	if !liked {
+		return
+	}
+
+	pending := &pendingLike{liked: true, authorUID: authorUID, event: event}
+	pending.timer = time.AfterFunc(d.coalesceWindow, func() { d.flushLike(key) })
+	d.likes[key] = pending
+}
+
+func (d *EventDispatcher) flushLike(key likeKey) {
+	d.mu.Lock()
+	pending, ok := d.likes[key]
+	delete(d.likes, key)
+	d.mu.Unlock()
+
+	if ok && pending.liked {
+		d.Publish([]string{pending.authorUID}, "post_liked", pending.event)
+	}
+}
+
+// PostCommented 通知作者动态有新评论，自己评论自己的动态不通知
+func (d *EventDispatcher) PostCommented(authorUID string, event PostCommentedEvent) {
+	if authorUID == event.FromUID {
+		return
+	}
+	d.Publish([]string{authorUID}, "post_commented", event)
+}
@@ -0,0 +1,592 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxFavoriteFolders    = 50
+	maxFolderNameLength   = 20
+	maxFavoriteNoteLength = 200
+)
+
+var (
+	errFavoriteSourceNotFound = errors.New("收藏的内容不存在")
+	errFavoriteSourceType     = errors.New("不支持收藏该类型的内容")
+	errFolderNotFound         = errors.New("收藏夹不存在")
+)
+
+type createFavoriteRequest struct {
+	SourceType string `json:"source_type" binding:"required"`
+	SourceID   int64  `json:"source_id" binding:"required"`
+	FolderID   *int64 `json:"folder_id"`
+	Note       string `json:"note"`
+}
+
+type updateFavoriteRequest struct {
+	FolderID *int64  `json:"folder_id"` // 0 表示移出收藏夹
+	Note     *string `json:"note"`
+}
+
+type favoriteFolderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// FavoriteFolderResponse 收藏夹及其中的收藏数
+type FavoriteFolderResponse struct {
+	FolderID  int64  `json:"folder_id"`
+	Name      string `json:"name"`
+	Count     int64  `json:"count"`
+	CreatedAt string `json:"created_at"`
+}
+
+// messageSnapshotExtra 生成消息快照的附加信息，保留消息类型和原始 extra
+func messageSnapshotExtra(msgType, extra string, fields map[string]interface{}) string {
+	fields["type"] = msgType
+	if extra != "" && json.Valid([]byte(extra)) {
+		fields["extra"] = json.RawMessage(extra)
+	}
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
+
+// snapshotFavoriteSource 检查用户能否查看来源内容并生成快照
+func snapshotFavoriteSource(db *gorm.DB, uid, sourceType string, sourceID int64) (*model.Favorite, error) {
+	favorite := &model.Favorite{
+		UID:        uid,
+		SourceType: sourceType,
+		SourceID:   sourceID,
+	}
+
+	switch sourceType {
+	case model.FavoriteSourcePost:
+		var post model.Post
+		if err := db.Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+			First(&post, sourceID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, errFavoriteSourceNotFound
+			}
+			return nil, err
+		}
+		if visible, err := canViewPost(db, uid, &post); err != nil {
+			return nil, err
+		} else if !visible {
+			return nil, errFavoriteSourceNotFound
+		}
+		fillLegacyPostImage(&post)
+		images := make([]string, 0, len(post.Images))
+		for _, image := range post.Images {
+			images = append(images, image.URL)
+		}
+		extra, _ := json.Marshal(map[string]interface{}{"images": images})
+
+		favorite.Title = post.Title
+		favorite.Content = post.Content
+		favorite.Extra = string(extra)
+		favorite.AuthorUID = post.UserID
+		favorite.SourceCreatedAt = post.CreatedAt
+
+	case model.FavoriteSourcePrivateMessage:
+		var message model.PrivateMessage
+		// 只能收藏自己参与的私聊消息
+		if err := db.Where("id = ? AND (sender_id = ? OR receiver_id = ?)", sourceID, uid, uid).
+			First(&message).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, errFavoriteSourceNotFound
+			}
+			return nil, err
+		}
+		peer := message.ReceiverID
+		if peer == uid {
+			peer = message.SenderID
+		}
+		favorite.Content = message.Content
+		favorite.Extra = messageSnapshotExtra(message.Type, message.Extra, map[string]interface{}{"conversation": peer})
+		favorite.AuthorUID = message.SenderID
+		favorite.SourceCreatedAt = message.Timestamp
+
+	case model.FavoriteSourceGroupMessage:
+		var message model.GroupMessage
+		// 只能收藏当前所在群的消息
+		if err := db.Where("id = ? AND group_id IN (?)", sourceID,
+			db.Model(&model.GroupMember{}).Select("CAST(gid AS CHAR)").Where("uid = ?", uid)).
+			First(&message).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, errFavoriteSourceNotFound
+			}
+			return nil, err
+		}
+		favorite.Content = message.Content
+		favorite.Extra = messageSnapshotExtra(message.Type, message.Extra, map[string]interface{}{"group_id": message.GroupID})
+		favorite.AuthorUID = message.SenderID
+		favorite.SourceCreatedAt = message.Timestamp
+
+	default:
+		return nil, errFavoriteSourceType
+	}
+
+	var author model.User
+	if err := db.Select("name").Where("uid = ?", favorite.AuthorUID).First(&author).Error; err == nil {
+		favorite.AuthorName = author.Name
+	}
+	return favorite, nil
+}
+
+// checkFolderOwner 确认收藏夹属于当前用户
+func checkFolderOwner(db *gorm.DB, uid string, folderID int64) error {
+	var count int64
+	if err := db.Model(&model.FavoriteFolder{}).Where("folder_id = ? AND uid = ?", folderID, uid).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errFolderNotFound
+	}
+	return nil
+}
+
+// validateFavoriteNote 校验收藏备注长度
+func validateFavoriteNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxFavoriteNoteLength {
+		return "", errors.New("备注不能超过200个字符")
+	}
+	return note, nil
+}
+
+// validateFolderName 校验收藏夹名称
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("收藏夹名称不能为空")
+	}
+	if utf8.RuneCountInString(name) > maxFolderNameLength {
+		return "", errors.New("收藏夹名称不能超过20个字符")
+	}
+	return name, nil
+}
+
+// loadOwnFavorite 查询当前用户的收藏，失败时已写入响应
+func loadOwnFavorite(c *gin.Context, db *gorm.DB, uid string) (*model.Favorite, bool) {
+	favoriteID, err := strconv.ParseInt(c.Param("favorite_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的收藏ID"})
+		return nil, false
+	}
+
+	var favorite model.Favorite
+	if err := db.Where("favorite_id = ? AND uid = ?", favoriteID, uid).First(&favorite).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "收藏不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询收藏失败"})
+		}
+		return nil, false
+	}
+	return &favorite, true
+}
+
+// loadOwnFolder 查询当前用户的收藏夹，失败时已写入响应
+func loadOwnFolder(c *gin.Context, db *gorm.DB, uid string) (*model.FavoriteFolder, bool) {
+	folderID, err := strconv.ParseInt(c.Param("folder_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的收藏夹ID"})
+		return nil, false
+	}
+
+	var folder model.FavoriteFolder
+	if err := db.Where("folder_id = ? AND uid = ?", folderID, uid).First(&folder).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": errFolderNotFound.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询收藏夹失败"})
+		}
+		return nil, false
+	}
+	return &folder, true
+}
+
+// getFavoritesHandler 分页获取收藏，可按收藏夹、来源类型筛选，keyword 搜索标题、内容和备注
+func getFavoritesHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	query := db.Model(&model.Favorite{}).Where("uid = ?", userID)
+	if raw := c.Query("folder_id"); raw != "" {
+		folderID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的收藏夹ID"})
+			return
+		}
+		if folderID == 0 {
+			query = query.Where("folder_id IS NULL")
+		} else {
+			query = query.Where("folder_id = ?", folderID)
+		}
+	}
+	if sourceType := c.Query("source_type"); sourceType != "" {
+		query = query.Where("source_type = ?", sourceType)
+	}
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		if utf8.RuneCountInString(keyword) > maxKeywordLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词过长"})
+			return
+		}
+		cond, args := matchCondition([]string{"title", "content", "note"}, keyword)
+		query = query.Where(cond, args...)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏失败"})
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	favorites := []model.Favorite{}
+	if err := query.Order("created_at DESC, favorite_id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&favorites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"favorites": favorites,
+		"total":     total,
+		"page":      page,
+	})
+}
+
+// createFavoriteHandler 收藏动态或聊天消息，保存当前内容快照
+func createFavoriteHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req createFavoriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	note, err := validateFavoriteNote(req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	if req.FolderID != nil && *req.FolderID != 0 {
+		if err := checkFolderOwner(db, userID, *req.FolderID); err != nil {
+			if err == errFolderNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "查询收藏夹失败"})
+			}
+			return
+		}
+	}
+
+	var count int64
+	if err := db.Model(&model.Favorite{}).
+		Where("uid = ? AND source_type = ? AND source_id = ?", userID, req.SourceType, req.SourceID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "已经收藏过了"})
+		return
+	}
+
+	favorite, err := snapshotFavoriteSource(db, userID, req.SourceType, req.SourceID)
+	if err != nil {
+		switch err {
+		case errFavoriteSourceType:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errFavoriteSourceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
+		}
+		return
+	}
+
+	now := time.Now()
+	if req.FolderID != nil && *req.FolderID != 0 {
+		favorite.FolderID = req.FolderID
+	}
+	favorite.Note = note
+	favorite.CreatedAt = now
+	favorite.UpdatedAt = now
+	if err := db.Create(favorite).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
+		return
+	}
+	c.JSON(http.StatusOK, favorite)
+}
+
+// updateFavoriteHandler 修改收藏的备注或所在收藏夹
+func updateFavoriteHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req updateFavoriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	favorite, ok := loadOwnFavorite(c, db, userID)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Note != nil {
+		note, err := validateFavoriteNote(*req.Note)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["note"] = note
+		favorite.Note = note
+	}
+	if req.FolderID != nil {
+		if *req.FolderID == 0 {
+			updates["folder_id"] = nil
+			favorite.FolderID = nil
+		} else {
+			if err := checkFolderOwner(db, userID, *req.FolderID); err != nil {
+				if err == errFolderNotFound {
+					c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "查询收藏夹失败"})
+				}
+				return
+			}
+			updates["folder_id"] = *req.FolderID
+			favorite.FolderID = req.FolderID
+		}
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要修改的内容"})
+		return
+	}
+
+	favorite.UpdatedAt = time.Now()
+	updates["updated_at"] = favorite.UpdatedAt
+	if err := db.Model(&model.Favorite{}).Where("favorite_id = ?", favorite.FavoriteID).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改收藏失败"})
+		return
+	}
+	c.JSON(http.StatusOK, favorite)
+}
+
+// deleteFavoriteHandler 取消收藏
+func deleteFavoriteHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	favorite, ok := loadOwnFavorite(c, db, userID)
+	if !ok {
+		return
+	}
+	if err := db.Delete(favorite).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消收藏失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已取消收藏"})
+}
+
+// getFavoriteFoldersHandler 获取收藏夹列表及每个收藏夹中的收藏数
+func getFavoriteFoldersHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var folders []model.FavoriteFolder
+	if err := db.Where("uid = ?", userID).Order("created_at").Find(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏夹失败"})
+		return
+	}
+
+	var counts []struct {
+		FolderID int64
+		Total    int64
+	}
+	if err := db.Model(&model.Favorite{}).
+		Select("folder_id, COUNT(*) AS total").
+		Where("uid = ? AND folder_id IS NOT NULL", userID).
+		Group("folder_id").
+		Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏夹失败"})
+		return
+	}
+	countMap := make(map[int64]int64, len(counts))
+	for _, fc := range counts {
+		countMap[fc.FolderID] = fc.Total
+	}
+
+	result := make([]FavoriteFolderResponse, 0, len(folders))
+	for _, folder := range folders {
+		result = append(result, FavoriteFolderResponse{
+			FolderID:  folder.FolderID,
+			Name:      folder.Name,
+			Count:     countMap[folder.FolderID],
+			CreatedAt: folder.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"folders": result})
+}
+
+// createFavoriteFolderHandler 新建收藏夹
+func createFavoriteFolderHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req favoriteFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	name, err := validateFolderName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var folders []model.FavoriteFolder
+	if err := db.Where("uid = ?", userID).Find(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建收藏夹失败"})
+		return
+	}
+	if len(folders) >= maxFavoriteFolders {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "收藏夹数量已达上限"})
+		return
+	}
+	for _, folder := range folders {
+		if strings.EqualFold(folder.Name, name) {
+			c.JSON(http.StatusConflict, gin.H{"error": "收藏夹名称已存在"})
+			return
+		}
+	}
+
+	folder := model.FavoriteFolder{UID: userID, Name: name, CreatedAt: time.Now()}
+	if err := db.Create(&folder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建收藏夹失败"})
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+// renameFavoriteFolderHandler 重命名收藏夹
+func renameFavoriteFolderHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req favoriteFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	name, err := validateFolderName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	folder, ok := loadOwnFolder(c, db, userID)
+	if !ok {
+		return
+	}
+
+	var count int64
+	if err := db.Model(&model.FavoriteFolder{}).
+		Where("uid = ? AND name = ? AND folder_id != ?", userID, name, folder.FolderID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改收藏夹失败"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "收藏夹名称已存在"})
+		return
+	}
+
+	if err := db.Model(folder).Update("name", name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改收藏夹失败"})
+		return
+	}
+	folder.Name = name
+	c.JSON(http.StatusOK, folder)
+}
+
+// deleteFavoriteFolderHandler 删除收藏夹，其中的收藏移出收藏夹而不删除
+func deleteFavoriteFolderHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	folder, ok := loadOwnFolder(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Favorite{}).Where("folder_id = ?", folder.FolderID).
+			Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(folder).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除收藏夹失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "收藏夹已删除"})
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"NetherLink-server/pkg/database"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+)
+
+// handleGroupChat 处理群聊消息：校验成员身份，解析@并写入 Extra，保存后转发给在线群成员
+func (s *WSServer) handleGroupChat(wsConn *WSConnection, chatPayload *ChatPayload, sharedPostID int64, hits []ModerationHit) error {
+	gid, err := strconv.Atoi(chatPayload.To)
+	if err != nil {
+		return errors.New("无效的群聊ID")
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		return errors.New("数据库连接失败")
+	}
+
+	var member model.GroupMember
+	if err := db.Where("gid = ? AND uid = ?", gid, wsConn.uid).First(&member).Error; err != nil {
+		return errors.New("你不是该群成员")
+	}
+
+	handles, mentionAll := parseMentionHandles(chatPayload.Content)
+	if mentionAll && member.Role != "owner" && member.Role != "admin" {
+		return errors.New("只有群主和管理员可以@所有人")
+	}
+
+	var memberUIDs []string
+	if err := db.Model(&model.GroupMember{}).Where("gid = ?", gid).Pluck("uid", &memberUIDs).Error; err != nil {
+		return errors.New("查询群成员失败")
+	}
+	isMember := make(map[string]bool, len(memberUIDs))
+	for _, uid := range memberUIDs {
+		isMember[uid] = true
+	}
+
+	// 只保留群内成员的@
+	users, err := resolveMentions(db, wsConn.uid, handles)
+	if err != nil {
+		return errors.New("解析提及失败")
+	}
+	mentioned := make([]MentionedUser, 0, len(users))
+	mentionedUIDs := make([]string, 0, len(users))
+	for _, user := range users {
+		if isMember[user.UID] {
+			mentioned = append(mentioned, user)
+			mentionedUIDs = append(mentionedUIDs, user.UID)
+		}
+	}
+
+	// 客户端传入的 Extra 需要是JSON对象，提及信息合并到其中
+	extra := map[string]interface{}{}
+	if chatPayload.Extra != "" {
+		if err := json.Unmarshal([]byte(chatPayload.Extra), &extra); err != nil {
+			return errors.New("无效的消息附加信息")
+		}
+	}
+	// 提及信息只能由服务端生成，丢弃客户端伪造的字段
+	delete(extra, "mentions")
+	delete(extra, "mention_all")
+	if len(mentioned) > 0 {
+		extra["mentions"] = mentioned
+	}
+	if mentionAll {
+		extra["mention_all"] = true
+	}
+	extraData, err := json.Marshal(extra)
+	if err != nil {
+		return errors.New("生成消息失败")
+	}
+
+	message := model.GroupMessage{
+		GroupID:   strconv.Itoa(gid),
+		SenderID:  wsConn.uid,
+		Timestamp: time.Now(),
+		Type:      chatPayload.Type,
+		Content:   chatPayload.Content,
+		Extra:     string(extraData),
+	}
+	if err := db.Create(&message).Error; err != nil {
+		return errors.New("保存消息失败")
+	}
+	flagForReview(db, model.ModerationSceneGroupMessage, strconv.FormatInt(message.ID, 10), wsConn.uid, message.Content, hits)
+	if sharedPostID != 0 {
+		if err := incrementShareCount(db, sharedPostID); err != nil {
+			log.Printf("更新分享次数失败: %v", err)
+		}
+	}
+
+	response := ChatResponse{
+		Success:      true,
+		Message:      "发送成功",
+		MessageID:    message.ID,
+		From:         wsConn.uid,
+		Content:      message.Content,
+		Type:         message.Type,
+		Extra:        message.Extra,
+		Timestamp:    message.Timestamp,
+		Conversation: message.GroupID,
+		IsGroup:      true,
+	}
+	s.PushEvent([]string{wsConn.uid}, "chat_response", response)
+
+	others := make([]string, 0, len(memberUIDs))
+	for _, uid := range memberUIDs {
+		if uid != wsConn.uid {
+			others = append(others, uid)
+		}
+	}
+	s.PushEvent(others, "chat", response)
+
+	added, err := syncMentions(db, model.MentionSourceGroupMessage, message.ID, wsConn.uid, mentionedUIDs)
+	if err != nil {
+		log.Printf("保存提及失败: %v", err)
+	}
+	// @all 时通知全部成员，否则只通知被@的人
+	notify := added
+	if mentionAll {
+		notify = others
+	}
+	if len(notify) > 0 {
+		notification := newMentionNotification(db, model.MentionSourceGroupMessage, message.ID, wsConn.uid, message.Content)
+		notification.GroupID = gid
+		notification.MentionAll = mentionAll
+		s.PushEvent(notify, "mention", notification)
+	}
+	return nil
+}
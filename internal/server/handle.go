@@ -0,0 +1,123 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// 用户ID：字母开头，4~20位字母、数字或下划线
+	handlePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{3,19}$`)
+	// 注册时自动生成的ID为8位十六进制，保留给系统使用
+	generatedIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}$`)
+)
+
+// reservedHandles 内置保留ID，不区分大小写
+var reservedHandles = []string{
+	"admin", "administrator", "root", "system", "sys", "support", "service", "help",
+	"security", "moderator", "official", "netherlink", "staff", "team", "bot",
+	"null", "undefined", "api", "www", "all", "everyone",
+}
+
+var errHandleTaken = errors.New("该用户ID已被占用")
+
+// mysqlDuplicateEntry 违反唯一索引时MySQL返回的错误码
+const mysqlDuplicateEntry = 1062
+
+type changeHandleRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// validateHandle 校验自定义用户ID的格式和保留词
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return errors.New("用户ID需以字母开头，由4~20位字母、数字或下划线组成")
+	}
+	if generatedIDPattern.MatchString(handle) {
+		return errors.New("该用户ID为系统保留格式")
+	}
+
+	lower := strings.ToLower(handle)
+	for _, list := range [][]string{reservedHandles, config.GlobalConfig.Handle.Reserved} {
+		for _, word := range list {
+			if lower == strings.ToLower(word) {
+				return errors.New("该用户ID为系统保留")
+			}
+		}
+	}
+	if strings.Contains(lower, "netherlink") || strings.Contains(lower, "official") {
+		return errors.New("该用户ID为系统保留")
+	}
+	return nil
+}
+
+// isDuplicateKeyError 是否为违反唯一索引的错误
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// changeHandleHandler 设置自定义用户ID，两次修改之间有冷却时间
+func (s *HTTPServer) changeHandleHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req changeHandleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	handle := strings.TrimSpace(req.ID)
+	if err := validateHandle(handle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	if user.ID == handle {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "与当前用户ID相同"})
+		return
+	}
+
+	cooldown := config.GlobalConfig.Handle.ChangeCooldown
+	if user.IDChangedAt != nil && time.Since(*user.IDChangedAt) < cooldown {
+		next := user.IDChangedAt.Add(cooldown)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          fmt.Sprintf("用户ID修改过于频繁，请在%s之后再试", next.Format("2006-01-02 15:04:05")),
+			"next_change_at": next.Format("2006-01-02 15:04:05"),
+		})
+		return
+	}
+
+	// 数据库排序规则不区分大小写，仅大小写不同也视为已占用
+	var count int64
+	db.Model(&model.User{}).Where("id = ? AND uid != ?", handle, userID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errHandleTaken.Error()})
+		return
+	}
+
+	s.applyProfileUpdates(c, userID, map[string]interface{}{
+		"id":            handle,
+		"id_changed_at": time.Now(),
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -28,18 +29,9 @@ import (
 	"time"
 )
 
-var (
-	codeStore      = make(map[string]codeEntry)
-	codeStoreMutex sync.Mutex
-)
-
-type codeEntry struct {
-	Code      string
-	ExpiresAt time.Time
-}
-
 type sendCodeRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Email   string `json:"email" binding:"required,email"`
+	Purpose string `json:"purpose"` // register（默认）或 reset_password
 }
 
 type registerRequest struct {
@@ -56,11 +48,14 @@ type loginRequest struct {
 }
 
 type loginResponse struct {
-	UID       string `json:"uid"`
-	User      string `json:"user"`
-	Email     string `json:"email"`
-	AvatarURL string `json:"avatar_url"`
-	Token     string `json:"token"`
+	UID               string `json:"uid"`
+	User              string `json:"user"`
+	Email             string `json:"email"`
+	AvatarURL         string `json:"avatar_url"`
+	Token             string `json:"token"`
+	RefreshToken      string `json:"refresh_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+	DeletionCancelled bool   `json:"deletion_cancelled,omitempty"` // 冷静期内登录，已撤销注销申请
 }
 
 type contactRequest struct {
@@ -69,12 +64,15 @@ type contactRequest struct {
 }
 
 type createPostRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	Visibility  string   `json:"visibility"`   // public/friends/private/custom，默认public
+	VisibleUIDs []string `json:"visible_uids"` // visibility为custom时可见的好友
 }
 
 type createCommentRequest struct {
-	Content string `json:"content" binding:"required"`
+	Content         string `json:"content" binding:"required"`
+	ParentCommentID *int64 `json:"parent_comment_id"` // 回复的评论ID，为空表示一级评论
 }
 
 // SearchUserResponse 用户搜索响应结构
@@ -97,6 +95,8 @@ type SearchGroupResponse struct {
 
 type HTTPServer struct {
 	engine *gin.Engine
+	ws     *WSServer
+	events *EventDispatcher
 }
 
 // AIHandler 处理AI对话的WebSocket连接
@@ -116,10 +116,18 @@ func NewAIHandler(conn *websocket.Conn, userID string) *AIHandler {
 	}
 }
 
-func NewHTTPServer(engine *gin.Engine) *HTTPServer {
+func NewHTTPServer(engine *gin.Engine, ws *WSServer) *HTTPServer {
 	server := &HTTPServer{
 		engine: engine,
-	}
+		ws:     ws,
+		events: newEventDispatcher(ws, config.GlobalConfig.Event),
+	}
+	codeStore = newVerificationStore(config.GlobalConfig.Verification)
+	loginLimiter = newLoginGuard(config.GlobalConfig.LoginGuard)
+	startAccountPurger(config.GlobalConfig.Account.PurgeInterval)
+	startStorySweeper(config.GlobalConfig.Story.SweepInterval)
+	moderator = newContentModerator(config.GlobalConfig.Moderation)
+	startModerationReloader(moderator, config.GlobalConfig.Moderation.ReloadInterval)
 	server.setupRoutes()
 	return server
 }
@@ -128,20 +136,80 @@ func (s *HTTPServer) setupRoutes() {
 	s.engine.POST("/api/send_code", sendCodeHandler)
 	s.engine.Static(config.GlobalConfig.Image.URLPrefix, config.GlobalConfig.Image.UploadDir)
 	s.engine.Static("/uploads/posts", "uploads/posts")
+	s.engine.Static("/uploads/stories", "uploads/stories")
 	s.engine.GET("/favicon.ico", func(c *gin.Context) {
 		c.Status(http.StatusNoContent)
 	})
 	s.engine.POST("/api/upload_image", uploadImageHandler)
 	s.engine.POST("/api/register", registerHandler)
 	s.engine.POST("/api/login", loginHandler)
+	s.engine.POST("/api/token/refresh", s.refreshTokenHandler)
+	s.engine.POST("/api/logout", authMiddleware(), s.logoutHandler)
+	s.engine.POST("/api/logout_all", authMiddleware(), s.logoutAllHandler)
+	s.engine.POST("/api/password/reset", s.resetPasswordHandler)
+	s.engine.POST("/api/account/send_code", authMiddleware(), accountSendCodeHandler)
+	s.engine.POST("/api/account/email", authMiddleware(), s.changeEmailHandler)
+	s.engine.POST("/api/account/delete", authMiddleware(), s.deleteAccountHandler)
+	s.engine.GET("/api/account/export", authMiddleware(), exportAccountHandler)
+	s.engine.GET("/api/login_history", authMiddleware(), getLoginHistoryHandler)
+	s.engine.POST("/api/login/2fa", twoFactorLoginHandler)
+	s.engine.GET("/api/2fa", authMiddleware(), getTwoFactorStatusHandler)
+	s.engine.POST("/api/2fa/setup", authMiddleware(), setupTwoFactorHandler)
+	s.engine.POST("/api/2fa/confirm", authMiddleware(), confirmTwoFactorHandler)
+	s.engine.POST("/api/2fa/recovery_codes", authMiddleware(), regenerateRecoveryCodesHandler)
+	s.engine.POST("/api/2fa/disable", authMiddleware(), disableTwoFactorHandler)
+	s.engine.GET("/api/profile", authMiddleware(), getMyProfileHandler)
+	s.engine.PATCH("/api/profile", authMiddleware(), readOnlyGuard(), s.updateProfileHandler)
+	s.engine.POST("/api/profile/avatar", authMiddleware(), readOnlyGuard(), s.uploadAvatarHandler)
+	s.engine.PUT("/api/profile/handle", authMiddleware(), readOnlyGuard(), s.changeHandleHandler)
+	s.engine.GET("/api/users/:uid/profile", authMiddleware(), getUserProfileHandler)
+	s.engine.GET("/api/users/:uid/posts", authMiddleware(), getUserPostsHandler)
+	s.engine.GET("/api/privacy", authMiddleware(), getPrivacyHandler)
+	s.engine.PUT("/api/privacy", authMiddleware(), updatePrivacyHandler)
 	s.engine.GET("/api/contacts", authMiddleware(), getContactsHandler)
 	s.engine.GET("/api/search/users", authMiddleware(), searchUsersHandler)
 	s.engine.GET("/api/search/groups", authMiddleware(), searchGroupsHandler)
+	s.engine.GET("/api/search/posts", authMiddleware(), searchPostsHandler)
+	s.engine.GET("/api/search/comments", authMiddleware(), searchCommentsHandler)
+	s.engine.GET("/api/search/messages", authMiddleware(), searchMessagesHandler)
+	s.engine.GET("/api/favorites", authMiddleware(), getFavoritesHandler)
+	s.engine.POST("/api/favorites", authMiddleware(), createFavoriteHandler)
+	s.engine.PUT("/api/favorites/:favorite_id", authMiddleware(), updateFavoriteHandler)
+	s.engine.DELETE("/api/favorites/:favorite_id", authMiddleware(), deleteFavoriteHandler)
+	s.engine.GET("/api/favorite-folders", authMiddleware(), getFavoriteFoldersHandler)
+	s.engine.POST("/api/favorite-folders", authMiddleware(), createFavoriteFolderHandler)
+	s.engine.PUT("/api/favorite-folders/:folder_id", authMiddleware(), renameFavoriteFolderHandler)
+	s.engine.DELETE("/api/favorite-folders/:folder_id", authMiddleware(), deleteFavoriteFolderHandler)
 	s.engine.GET("/api/posts", authMiddleware(), getPostsHandler)
-	s.engine.POST("/api/posts", authMiddleware(), createPostHandler)
+	s.engine.POST("/api/posts", authMiddleware(), readOnlyGuard(), s.createPostHandler)
 	s.engine.GET("/api/posts/:post_id", authMiddleware(), getPostDetailHandler)
-	s.engine.POST("/api/posts/:post_id/comments", authMiddleware(), createCommentHandler)
-	s.engine.POST("/api/posts/:post_id/like", authMiddleware(), togglePostLikeHandler)
+	s.engine.PUT("/api/posts/:post_id", authMiddleware(), readOnlyGuard(), s.updatePostHandler)
+	s.engine.DELETE("/api/posts/:post_id", authMiddleware(), deletePostHandler)
+	s.engine.GET("/api/posts/:post_id/comments", authMiddleware(), getCommentsHandler)
+	s.engine.POST("/api/posts/:post_id/comments", authMiddleware(), readOnlyGuard(), s.createCommentHandler)
+	s.engine.GET("/api/posts/:post_id/comments/:comment_id/replies", authMiddleware(), getCommentRepliesHandler)
+	s.engine.POST("/api/posts/:post_id/comments/:comment_id/like", authMiddleware(), toggleCommentLikeHandler)
+	s.engine.PUT("/api/posts/:post_id/comments/:comment_id", authMiddleware(), readOnlyGuard(), s.updateCommentHandler)
+	s.engine.DELETE("/api/posts/:post_id/comments/:comment_id", authMiddleware(), deleteCommentHandler)
+	s.engine.POST("/api/posts/:post_id/like", authMiddleware(), s.togglePostLikeHandler)
+	s.engine.POST("/api/posts/:post_id/repost", authMiddleware(), readOnlyGuard(), s.repostHandler)
+	s.engine.GET("/api/post-cards", authMiddleware(), getPostCardsHandler)
+	s.engine.GET("/api/tags/trending", authMiddleware(), getTrendingTagsHandler)
+	s.engine.GET("/api/tags/:name/posts", authMiddleware(), getTagPostsHandler)
+	s.engine.POST("/api/stories", authMiddleware(), readOnlyGuard(), createStoryHandler)
+	s.engine.GET("/api/stories/ring", authMiddleware(), getStoryRingHandler)
+	s.engine.POST("/api/stories/:story_id/view", authMiddleware(), viewStoryHandler)
+	s.engine.GET("/api/stories/:story_id/viewers", authMiddleware(), getStoryViewersHandler)
+	s.engine.DELETE("/api/stories/:story_id", authMiddleware(), deleteStoryHandler)
+	s.engine.GET("/api/users/:uid/stories", authMiddleware(), getUserStoriesHandler)
+	s.engine.POST("/api/reports", authMiddleware(), createReportHandler)
+	s.engine.GET("/api/reports", authMiddleware(), getMyReportsHandler)
+	s.engine.GET("/api/admin/reports", authMiddleware(), adminMiddleware(), getAdminReportsHandler)
+	s.engine.POST("/api/admin/reports/:report_id/handle", authMiddleware(), adminMiddleware(), s.handleReportHandler)
+	s.engine.GET("/api/admin/audit-logs", authMiddleware(), adminMiddleware(), getAuditLogsHandler)
+	s.engine.POST("/api/admin/users/:uid/suspend", authMiddleware(), adminMiddleware(), s.suspendUserHandler)
+	s.engine.POST("/api/admin/users/:uid/unsuspend", authMiddleware(), adminMiddleware(), liftSuspensionHandler)
+	s.engine.GET("/api/admin/users/:uid/sanctions", authMiddleware(), adminMiddleware(), getUserSanctionsHandler)
 	s.engine.GET("/ws/ai", authMiddleware(), s.handleAIWebSocket)
 }
 
@@ -151,20 +219,39 @@ func sendCodeHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
+	if req.Purpose == "" {
+		req.Purpose = codePurposeRegister
+	}
 
-	code := generateCode(6)
-	expiresAt := time.Now().Add(3 * time.Minute)
+	// 未登录状态只能申请注册和重置密码的验证码
+	if req.Purpose != codePurposeRegister && req.Purpose != codePurposeResetPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的验证码用途"})
+		return
+	}
 
-	codeStoreMutex.Lock()
-	codeStore[req.Email] = codeEntry{Code: code, ExpiresAt: expiresAt}
-	codeStoreMutex.Unlock()
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
 
-	emailCfg := config.GlobalConfig.Email
-	sender := utils.NewEmailSender(emailCfg.SMTPHost, emailCfg.SMTPPort, emailCfg.Sender, emailCfg.DisplayName, emailCfg.Password, emailCfg.UseSSL)
+	var count int64
+	db.Model(&model.User{}).Where("email = ?", req.Email).Count(&count)
+	if req.Purpose == codePurposeRegister && count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱已存在"})
+		return
+	}
+	// 重置密码时邮箱未注册也返回相同结果，避免被用来探测邮箱是否注册
+	if req.Purpose == codePurposeResetPassword && count == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "验证码已发送"})
+		return
+	}
 
-	template := utils.GetEmailTemplate(code)
-	err := sender.Send(req.Email, "NetherLink 注册验证码", template)
-	if err != nil {
+	if err := sendVerificationCode(req.Purpose, req.Email, c.ClientIP()); err != nil {
+		if err == ErrCodeCooldown {
+			respondCodeError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "邮件发送失败"})
 		return
 	}
@@ -172,13 +259,6 @@ func sendCodeHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "验证码已发送"})
 }
 
-func generateCode(length int) string {
-	rand.Seed(time.Now().UnixNano())
-	min := int64(100000)
-	max := int64(999999)
-	return fmt.Sprintf("%06d", rand.Int63n(max-min+1)+min)
-}
-
 func uploadImageHandler(c *gin.Context) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
@@ -187,35 +267,63 @@ func uploadImageHandler(c *gin.Context) {
 	}
 	defer file.Close()
 
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "仅支持jpg、jpeg、png、gif格式"})
+	filename, err := saveUploadedImage(file, header.Filename)
+	if err != nil {
+		if err == errUnsupportedImage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
+	url := utils.GetFullImageURL(filename)
+	c.JSON(http.StatusOK, gin.H{"url": url})
+}
+
+var errUnsupportedImage = errors.New("仅支持jpg、jpeg、png、gif格式")
+
+// saveUploadedImage 将上传的图片保存到图片目录，返回生成的文件名
+func saveUploadedImage(file io.Reader, originalName string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(originalName))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" {
+		return "", errUnsupportedImage
+	}
+
 	filename := generateImageFilename(ext)
 	savePath := utils.GetImageSavePath(filename)
 
 	// 确保目录存在
 	if err := os.MkdirAll(filepath.Dir(savePath), 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建目录失败"})
-		return
+		return "", errors.New("创建目录失败")
 	}
 
 	out, err := os.Create(savePath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
-		return
+		return "", errors.New("保存文件失败")
 	}
 	defer out.Close()
 
 	if _, err := io.Copy(out, file); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "写入文件失败"})
-		return
+		// 写入失败时删除不完整的文件
+		out.Close()
+		os.Remove(savePath)
+		return "", errors.New("写入文件失败")
 	}
 
-	url := utils.GetFullImageURL(filename)
-	c.JSON(http.StatusOK, gin.H{"url": url})
+	return filename, nil
+}
+
+// removeUploadedImage 删除上传目录中的图片，非本站上传的图片地址直接忽略
+func removeUploadedImage(url string) {
+	prefix := utils.GetFullImageURL("")
+	if !strings.HasPrefix(url, prefix) {
+		return
+	}
+	filename := filepath.Base(strings.TrimPrefix(url, prefix))
+	if err := os.Remove(utils.GetImageSavePath(filename)); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除图片失败: %v", err)
+	}
 }
 
 func generateImageFilename(ext string) string {
@@ -231,12 +339,21 @@ func registerHandler(c *gin.Context) {
 		return
 	}
 
+	// 昵称与修改资料时的规则一致，同样经过敏感词过滤
+	name, err := validateName(req.User)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	nameHits, err := moderateFields(&name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 校验验证码
-	codeStoreMutex.Lock()
-	entry, ok := codeStore[req.Email]
-	codeStoreMutex.Unlock()
-	if !ok || entry.Code != req.VarifyCode || time.Now().After(entry.ExpiresAt) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码无效或已过期"})
+	if err := verifyCode(codePurposeRegister, req.Email, req.VarifyCode); err != nil {
+		respondCodeError(c, err)
 		return
 	}
 
@@ -255,9 +372,7 @@ func registerHandler(c *gin.Context) {
 	}
 
 	// md5加密密码
-	h := md5.New()
-	h.Write([]byte(req.Passwd))
-	passwdHash := hex.EncodeToString(h.Sum(nil))
+	passwdHash := hashPassword(req.Passwd)
 
 	// 生成UUID并获取前8位作为用户ID
 	fullUUID := generateUUID()
@@ -267,7 +382,7 @@ func registerHandler(c *gin.Context) {
 		UID:       fullUUID,
 		ID:        userID, // 使用UUID前8位作为用户ID
 		Email:     req.Email,
-		Name:      req.User, // 使用用户输入的名称作为显示名
+		Name:      name, // 使用用户输入的名称作为显示名
 		Password:  passwdHash,
 		AvatarURL: req.AvatarURL,
 		Status:    0, // 默认状态
@@ -279,6 +394,7 @@ func registerHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户失败"})
 		return
 	}
+	flagForReview(db, model.ModerationSceneUserName, user.UID, user.UID, user.Name, nameHits)
 
 	c.JSON(http.StatusOK, gin.H{
 		"uid":        user.UID,
@@ -301,35 +417,102 @@ func loginHandler(c *gin.Context) {
 		return
 	}
 
+	var user model.User
+	var account *model.User // 邮箱对应的账号，未注册时为nil，用于记录登录历史
+	if err := db.Where("email = ?", req.Email).First(&user).Error; err == nil {
+		account = &user
+	}
+
+	// 失败次数过多时暂时拒绝登录
+	if wait := loginLimiter.lockedFor(req.Email, c.ClientIP()); wait > 0 {
+		recordLogin(c, account, req.Email, false, loginReasonLocked)
+		respondLoginLocked(c, wait)
+		return
+	}
+
 	// md5加密密码
-	h := md5.New()
-	h.Write([]byte(req.Passwd))
-	passwdHash := hex.EncodeToString(h.Sum(nil))
+	passwdHash := hashPassword(req.Passwd)
 
-	var user model.User
-	if err := db.Where("email = ? AND password = ?", req.Email, passwdHash).First(&user).Error; err != nil {
+	if account == nil || user.Password != passwdHash {
+		failures, alert := loginLimiter.fail(req.Email, c.ClientIP())
+		recordLogin(c, account, req.Email, false, loginReasonWrongPassword)
+		if account != nil && alert {
+			sendLoginAlert(user.Email, c.ClientIP(), failures)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
 		return
 	}
+	if rejectSuspendedLogin(c, db, &user) {
+		return
+	}
 
-	token, err := generateJWT(user.UID)
+	// 开启两步验证的账号，需要再用challenge token和验证码换取登录令牌
+	enabled, err := twoFactorEnabled(db, user.UID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询两步验证状态失败"})
+		return
+	}
+	if enabled {
+		challenge, err := generateChallengeToken(user.UID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	respondLoginSuccess(c, db, &user)
+}
+
+// respondLoginSuccess 登录校验全部通过后创建会话并返回令牌
+func respondLoginSuccess(c *gin.Context, db *gorm.DB, user *model.User) {
+	loginLimiter.reset(user.Email)
+
+	tokens, err := createSession(db, user.UID, c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		return
 	}
+	recordLogin(c, user, user.Email, true, "")
 
 	c.JSON(http.StatusOK, loginResponse{
-		UID:       user.UID,
-		User:      user.ID,
-		Email:     user.Email,
-		AvatarURL: user.AvatarURL,
-		Token:     token,
+		UID:               user.UID,
+		User:              user.ID,
+		Email:             user.Email,
+		AvatarURL:         user.AvatarURL,
+		Token:             tokens.Token,
+		RefreshToken:      tokens.RefreshToken,
+		ExpiresIn:         tokens.ExpiresIn,
+		DeletionCancelled: cancelAccountDeletion(db, user),
+	})
+}
+
+// respondLoginLocked 登录被临时锁定时的响应
+func respondLoginLocked(c *gin.Context, wait time.Duration) {
+	retryAfter := int(wait.Seconds()) + 1
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("登录失败次数过多，请在%d秒后重试", retryAfter),
+		"retry_after": retryAfter,
 	})
 }
 
-func generateJWT(uid string) (string, error) {
+// hashPassword 计算密码的md5摘要
+func hashPassword(passwd string) string {
+	h := md5.New()
+	h.Write([]byte(passwd))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// generateJWT 签发绑定到会话的短期access token
+func generateJWT(uid, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"uid": uid,
+		"sid": sessionID,
+		"typ": "access",
 		"iss": "netherlink",
 		"exp": time.Now().Add(config.GlobalConfig.JWT.Expire).Unix(),
 	}
@@ -395,27 +578,47 @@ func authMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 解析JWT token
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.GlobalConfig.JWT.Secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": -1, "message": "无效的token"})
+		// 解析JWT token并检查会话是否已被吊销
+		uid, sessionID, err := parseAccessToken(parts[1])
+		if err != nil {
+			if err == errInvalidToken || err == errSessionRevoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"code": -1, "message": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+			}
 			c.Abort()
 			return
 		}
 
-		// 将用户信息存储到上下文
-		uid, ok := claims["uid"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": -1, "message": "无效的token格式"})
+		// 完全封禁的账号拒绝访问，只读封禁交给 readOnlyGuard 拦截发布类请求
+		db, err := getDB()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+			c.Abort()
+			return
+		}
+		suspension, err := activeSuspension(db, uid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询账号状态失败"})
+			c.Abort()
+			return
+		}
+		if suspension != nil && !suspension.ReadOnly {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":      -1,
+				"message":   suspensionMessage(suspension),
+				"suspended": newSuspensionInfo(suspension),
+			})
 			c.Abort()
 			return
 		}
 
+		// 将用户信息存储到上下文
 		c.Set("user_id", uid)
+		c.Set("session_id", sessionID)
+		if suspension != nil {
+			c.Set("suspension", suspension)
+		}
 		c.Next()
 	}
 }
@@ -470,8 +673,17 @@ func getContactsHandler(c *gin.Context) {
 		return
 	}
 
+	friendUIDs := make([]string, 0, len(friends))
+	for _, f := range friends {
+		friendUIDs = append(friendUIDs, f.UserID)
+	}
+	hidden := hiddenStatusUIDs(db, friendUIDs)
+
 	// 转换好友信息
 	for _, f := range friends {
+		if hidden[f.UserID] {
+			f.Status = 0
+		}
 		response.Friends = append(response.Friends, model.FriendInfo{
 			UserID:    f.UserID,
 			Name:      f.Name,
@@ -529,77 +741,41 @@ func getPostsHandler(c *gin.Context) {
 		return
 	}
 
+	cursor, err := parseFeedCursor(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+	limit := parseFeedLimit(c)
+
 	db, err := getDB()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
 		return
 	}
 
-	var posts []struct {
-		PostID     int64     `gorm:"column:post_id"`
-		Title      string    `gorm:"column:title"`
-		UserID     string    `gorm:"column:user_id"`
-		UserName   string    `gorm:"column:name"`
-		UserAvatar string    `gorm:"column:avatar_url"`
-		ImageURL   string    `gorm:"column:image_url"`
-		CreatedAt  time.Time `gorm:"column:created_at"`
+	// 按游标分页查询帖子和作者信息，feed=friends 时只看好友的动态
+	query := db.Table("posts").Scopes(visiblePostsScope(userID))
+	if c.Query("feed") == "friends" {
+		query = query.Scopes(friendsFeedScope(userID))
 	}
-
-	// 联表查询帖子和用户信息
-	err = db.Table("posts").
-		Select("posts.post_id, posts.title, posts.user_id, posts.image_url, users.name, users.avatar_url, posts.created_at").
-		Joins("LEFT JOIN users ON posts.user_id = users.uid").
-		Order("posts.created_at DESC").
-		Find(&posts).Error
-
+	rows, next, err := queryFeedPage(query, cursor, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子列表失败"})
 		return
 	}
 
-	// 获取每个帖子的点赞信息
-	var result []model.PostPreview
-	for _, post := range posts {
-		var preview model.PostPreview
-		preview.PostID = post.PostID
-		preview.Title = post.Title
-		preview.UserID = post.UserID
-		preview.UserName = post.UserName
-		preview.UserAvatar = &post.UserAvatar
-		preview.CreatedAt = post.CreatedAt.Format("2006-01-02 15:04:05")
-		if post.ImageURL != "" {
-			preview.FirstImage = &post.ImageURL
-		}
-
-		// 获取点赞数
-		var likesCount int64
-		if err := db.Model(&model.PostLike{}).
-			Where("post_id = ?", post.PostID).
-			Count(&likesCount).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取点赞数失败"})
-			return
-		}
-		preview.LikesCount = likesCount
-
-		// 检查当前用户是否点赞
-		var likeCount int64
-		if err := db.Model(&model.PostLike{}).
-			Where("post_id = ? AND user_id = ?", post.PostID, userID).
-			Count(&likeCount).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "检查点赞状态失败"})
-			return
-		}
-		preview.IsLiked = likeCount > 0
-
-		result = append(result, preview)
+	// 批量获取点赞、评论数和点赞状态
+	result, err := buildPostPreviews(db, userID, rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子统计失败"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"posts": result,
-	})
+	c.JSON(http.StatusOK, feedResponse(result, next))
 }
 
-func createPostHandler(c *gin.Context) {
+func (s *HTTPServer) createPostHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"code": -1, "message": "未授权"})
@@ -630,6 +806,17 @@ func createPostHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "标题和内容不能为空"})
 		return
 	}
+	hits, err := moderateFields(&req.Title, &req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	files := form.File["images"]
+	if err := validatePostImages(files); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
 
 	// 获取数据库连接
 	db, err := getDB()
@@ -638,52 +825,62 @@ func createPostHandler(c *gin.Context) {
 		return
 	}
 
-	// 创建帖子记录（先不设置图片URL）
-	post := &model.Post{
-		UserID:    userID,
-		Title:     req.Title,
-		Content:   req.Content,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	visibility, visibleUIDs, err := normalizeVisibility(db, userID, req.Visibility, req.VisibleUIDs)
+	if err != nil {
+		if err == errInvalidVisibility || err == errVisibleUsers {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询好友失败"})
+		}
+		return
 	}
 
-	if err := db.Create(post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "创建帖子失败"})
+	// 先保存图片文件，再在同一事务中写入帖子、标签、可见好友和图片记录
+	images, err := savePostImages(c, userID, files)
+	if err != nil {
+		if err == errPostImageType || err == errPostImageTooLarge {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": err.Error()})
+		}
 		return
 	}
 
-	// 处理上传的图片
-	files := form.File["images"]
-	if len(files) > 0 { // 有上传图片
-		file := files[0] // 只处理第一张图片
+	post := &model.Post{
+		UserID:     userID,
+		Title:      req.Title,
+		Content:    req.Content,
+		Visibility: visibility,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
 
-		// 检查文件类型
-		contentType := file.Header.Get("Content-Type")
-		if !strings.HasPrefix(contentType, "image/") {
-			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "只能上传图片文件"})
-			return
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
 		}
-
-		// 生成文件名和保存路径
-		ext := filepath.Ext(file.Filename)
-		filename := fmt.Sprintf("post_%d%s", post.PostID, ext)
-		savePath := filepath.Join("uploads/posts", filename)
-
-		// 保存文件
-		if err := c.SaveUploadedFile(file, savePath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "保存图片失败"})
-			return
+		if err := indexPostTags(tx, post); err != nil {
+			return err
 		}
-
-		// 更新帖子的图片URL
-		imageURL := fmt.Sprintf("%s/uploads/posts/%s", config.GlobalConfig.Server.HTTP.BaseURL, filename)
-		if err := db.Model(post).Update("image_url", imageURL).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "更新图片URL失败"})
-			return
+		if err := savePostVisibleUsers(tx, post.PostID, visibleUIDs); err != nil {
+			return err
+		}
+		if len(images) == 0 {
+			return nil
 		}
-		post.ImageURL = imageURL
+		for i := range images {
+			images[i].PostID = post.PostID
+		}
+		return tx.Create(&images).Error
+	}); err != nil {
+		removePostImageFiles(images)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "创建帖子失败"})
+		return
 	}
 
+	flagForReview(db, model.ModerationScenePost, strconv.FormatInt(post.PostID, 10), userID, post.Title+"\n"+post.Content, hits)
+	mentions := s.notifyPostMentions(db, post)
+
 	// 查询完整的帖子信息
 	var postInfo struct {
 		PostID     int64     `gorm:"column:post_id"`
@@ -716,7 +913,10 @@ func createPostHandler(c *gin.Context) {
 			"user_id":     postInfo.UserID,
 			"user_name":   postInfo.UserName,
 			"user_avatar": postInfo.UserAvatar,
-			"image_url":   postInfo.ImageURL,
+			"image_url":   firstImageURL(images),
+			"images":      images,
+			"visibility":  post.Visibility,
+			"mentions":    mentions,
 			"created_at":  postInfo.CreatedAt.Format("2006-01-02 15:04:05"),
 		},
 	})
@@ -742,7 +942,9 @@ func getPostDetailHandler(c *gin.Context) {
 
 	// 4. 查询帖子信息
 	var post model.Post
-	if err := db.Preload("Comments").Preload("Likes").First(&post, postID).Error; err != nil {
+	if err := db.Preload("Likes").
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		First(&post, postID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "帖子不存在"})
 		} else {
@@ -750,6 +952,15 @@ func getPostDetailHandler(c *gin.Context) {
 		}
 		return
 	}
+	if visible, err := canViewPost(db, userID, &post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询帖子失败"})
+		return
+	} else if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"error": errPostsHidden.Error()})
+		return
+	}
+
+	fillLegacyPostImage(&post)
 
 	// 5. 查询作者信息
 	var author model.User
@@ -767,45 +978,15 @@ func getPostDetailHandler(c *gin.Context) {
 		}
 	}
 
-	// 7. 查询所有评论用户的信息
-	var commentUsers = make(map[string]model.User)
-	var userIDs []string
-	for _, comment := range post.Comments {
-		userIDs = append(userIDs, comment.UserID)
-	}
-	if len(userIDs) > 0 {
-		var users []model.User
-		if err := db.Where("uid IN ?", userIDs).Find(&users).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询评论用户信息失败"})
-			return
-		}
-		for _, user := range users {
-			commentUsers[user.UID] = user
-		}
-	}
-
-	// 8. 构造带用户信息的评论
-	var commentsWithUser []gin.H
-	for _, comment := range post.Comments {
-		user, exists := commentUsers[comment.UserID]
-		commentData := gin.H{
-			"comment_id": comment.CommentID,
-			"post_id":    comment.PostID,
-			"user_id":    comment.UserID,
-			"content":    comment.Content,
-			"created_at": comment.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
-		if exists {
-			commentData["user_name"] = user.Name
-			commentData["user_avatar"] = user.AvatarURL
-		} else {
-			commentData["user_name"] = "未知用户"
-			commentData["user_avatar"] = ""
-		}
-		commentsWithUser = append(commentsWithUser, commentData)
+	// 7. 评论分页返回第一页，后续页通过评论列表接口获取
+	page, pageSize := parsePage(c)
+	comments, commentsTotal, err := listRootComments(db, userID, post.PostID, c.DefaultQuery("sort", commentSortTime), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
 	}
 
-	// 9. 构造响应
+	// 8. 构造响应
 	response := gin.H{
 		"post_id": post.PostID,
 		"title":   post.Title,
@@ -815,15 +996,35 @@ func getPostDetailHandler(c *gin.Context) {
 			"user_name": author.Name,
 			"avatar":    author.AvatarURL,
 		},
-		"is_liked":    isLiked,
-		"likes_count": len(post.Likes),
-		"comments":    commentsWithUser,
+		"images":         post.Images,
+		"visibility":     post.Visibility,
+		"share_count":    post.ShareCount,
+		"repost_of":      nil,
+		"edited_at":      formatOptionalTime(post.EditedAt),
+		"is_liked":       isLiked,
+		"likes_count":    len(post.Likes),
+		"comments":       comments,
+		"comments_total": commentsTotal,
+	}
+	if post.RepostOfID != nil {
+		cards, err := loadPostCards(db, userID, []int64{*post.RepostOfID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取原动态失败"})
+			return
+		}
+		response["repost_of"] = cards[*post.RepostOfID]
+	}
+	// 自定义可见范围只返回给作者本人
+	if post.UserID == userID && post.Visibility == model.VisibilityCustom {
+		var visibleUIDs []string
+		db.Model(&model.PostVisibleUser{}).Where("post_id = ?", post.PostID).Pluck("user_id", &visibleUIDs)
+		response["visible_uids"] = visibleUIDs
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-func createCommentHandler(c *gin.Context) {
+func (s *HTTPServer) createCommentHandler(c *gin.Context) {
 	// 1. 获取帖子ID
 	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
 	if err != nil {
@@ -846,6 +1047,11 @@ func createCommentHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "评论内容不能为空"})
 		return
 	}
+	hits, err := moderateFields(&req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 5. 获取数据库连接
 	db, err := getDB()
@@ -864,20 +1070,52 @@ func createCommentHandler(c *gin.Context) {
 		}
 		return
 	}
+	if visible, err := canViewPost(db, userID, &post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询帖子失败"})
+		return
+	} else if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"error": errPostsHidden.Error()})
+		return
+	}
 
-	// 7. 创建评论
+	// 7. 创建评论，回复统一挂在一级评论下，只展示两层
 	comment := model.Comment{
 		PostID:    postID,
 		UserID:    userID,
 		Content:   req.Content,
 		CreatedAt: time.Now(),
 	}
+	if req.ParentCommentID != nil {
+		var target model.Comment
+		if err := db.Where("comment_id = ? AND post_id = ?", *req.ParentCommentID, postID).First(&target).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "回复的评论不存在"})
+			return
+		}
+		rootID := target.CommentID
+		if target.ParentCommentID != nil {
+			rootID = *target.ParentCommentID
+		}
+		comment.ParentCommentID = &rootID
+		comment.ReplyToUserID = &target.UserID
+	}
 
-	if err := db.Create(&comment).Error; err != nil {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentCommentID == nil {
+			return nil
+		}
+		return tx.Model(&model.Comment{}).Where("comment_id = ?", *comment.ParentCommentID).
+			Update("reply_count", gorm.Expr("reply_count + 1")).Error
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建评论失败"})
 		return
 	}
 
+	flagForReview(db, model.ModerationSceneComment, strconv.FormatInt(comment.CommentID, 10), userID, comment.Content, hits)
+	mentions := s.notifyCommentMentions(db, &post, &comment)
+
 	// 8. 查询评论者信息
 	var user model.User
 	if err := db.First(&user, "uid = ?", userID).Error; err != nil {
@@ -885,19 +1123,35 @@ func createCommentHandler(c *gin.Context) {
 		return
 	}
 
-	// 9. 返回评论信息
+	// 9. 通知动态作者
+	s.events.PostCommented(post.UserID, PostCommentedEvent{
+		PostID:          post.PostID,
+		PostTitle:       post.Title,
+		CommentID:       comment.CommentID,
+		ParentCommentID: comment.ParentCommentID,
+		FromUID:         userID,
+		FromName:        user.Name,
+		FromAvatar:      user.AvatarURL,
+		Content:         contentExcerpt(comment.Content),
+		CreatedAt:       comment.CreatedAt.Format("2006-01-02 15:04:05"),
+	})
+
+	// 10. 返回评论信息
 	c.JSON(http.StatusOK, gin.H{
-		"comment_id":  comment.CommentID,
-		"post_id":     comment.PostID,
-		"user_id":     comment.UserID,
-		"user_name":   user.Name,
-		"user_avatar": user.AvatarURL,
-		"content":     comment.Content,
-		"created_at":  comment.CreatedAt.Format("2006-01-02 15:04:05"),
+		"comment_id":        comment.CommentID,
+		"post_id":           comment.PostID,
+		"user_id":           comment.UserID,
+		"user_name":         user.Name,
+		"user_avatar":       user.AvatarURL,
+		"content":           comment.Content,
+		"parent_comment_id": comment.ParentCommentID,
+		"reply_to_user_id":  comment.ReplyToUserID,
+		"mentions":          mentions,
+		"created_at":        comment.CreatedAt.Format("2006-01-02 15:04:05"),
 	})
 }
 
-func togglePostLikeHandler(c *gin.Context) {
+func (s *HTTPServer) togglePostLikeHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"code": -1, "message": "未授权"})
@@ -928,6 +1182,13 @@ func togglePostLikeHandler(c *gin.Context) {
 		}
 		return
 	}
+	if visible, err := canViewPost(db, userID, &post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询帖子失败"})
+		return
+	} else if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "message": errPostsHidden.Error()})
+		return
+	}
 
 	// 开启事务
 	tx := db.Begin()
@@ -973,10 +1234,22 @@ func togglePostLikeHandler(c *gin.Context) {
 		return
 	}
 
+	// 通知作者，短时间内反复点赞、取消只通知一次
+	var liker model.User
+	db.Select("name, avatar_url").Where("uid = ?", userID).First(&liker)
+	s.events.PostLikeToggled(post.UserID, !wasLiked, PostLikedEvent{
+		PostID:     post.PostID,
+		PostTitle:  post.Title,
+		FromUID:    userID,
+		FromName:   liker.Name,
+		FromAvatar: liker.AvatarURL,
+		CreatedAt:  time.Now().Format("2006-01-02 15:04:05"),
+	})
+
 	// 获取最新点赞数
 	var likesCount int64
 	if err := db.Model(&model.PostLike{}).Where("post_id = ?", postID).Count(&likesCount).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取点赞数失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子统计失败"})
 		return
 	}
 
@@ -1346,6 +1619,9 @@ func searchUsersHandler(c *gin.Context) {
 
 	var users []SearchUserResponse
 
+	// 用户ID按前缀匹配，昵称走全文索引
+	nameCond, nameArgs := matchCondition([]string{"u.name"}, keyword)
+
 	scoreQuery := `
     CASE 
         WHEN id = ? THEN 100
@@ -1364,10 +1640,14 @@ func searchUsersHandler(c *gin.Context) {
 			"%"+keyword+"%", "%"+keyword+"%",
 		).
 		Joins("LEFT JOIN friends f ON f.friend_id = u.uid AND f.user_id = ?", currentUID).
+		Joins("LEFT JOIN user_privacy p ON p.uid = u.uid").
 		Where("u.uid != ?", currentUID). // 排除自己
 		Where("f.friend_id IS NULL").    // 排除已添加的好友
-		Where(db.Where("u.id LIKE ?", "%"+keyword+"%").
-			Or("u.name LIKE ?", "%"+keyword+"%"),
+		Where("u.delete_at IS NULL").    // 排除注销中的账号
+		// 按隐私设置过滤：all 可模糊搜索，handle 仅完整用户ID可搜到，none 不出现
+		Where(db.Where("COALESCE(p.search_policy, ?) = ? AND (u.id LIKE ? OR "+nameCond+")",
+			append([]interface{}{model.SearchPolicyAll, model.SearchPolicyAll, escapeLike(keyword) + "%"}, nameArgs...)...).
+			Or("p.search_policy = ? AND u.id = ?", model.SearchPolicyHandle, keyword),
 		).
 		Order("relevance_score DESC").
 		Limit(20).
@@ -1376,6 +1656,17 @@ func searchUsersHandler(c *gin.Context) {
 		return
 	}
 
+	uids := make([]string, 0, len(users))
+	for _, u := range users {
+		uids = append(uids, u.UID)
+	}
+	hidden := hiddenStatusUIDs(db, uids)
+	for i := range users {
+		if hidden[users[i].UID] {
+			users[i].Status = 0
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"users": users})
 }
 
@@ -1418,13 +1709,14 @@ func searchGroupsHandler(c *gin.Context) {
 			ELSE 0
 		END as relevance_score`
 
-	// 构建搜索条件
+	// 构建搜索条件，群名走全文索引
+	nameCond, nameArgs := matchCondition([]string{"chat_groups.name"}, keyword)
 	searchQuery := query.Select("*, "+scoreQuery,
 		keyword,         // 完全匹配群名
 		keyword+"%",     // 群名前缀匹配
 		"%"+keyword+"%", // 群名包含关键词
 	).Where(
-		"name LIKE ?", "%"+keyword+"%",
+		nameCond, nameArgs...,
 	).Order("relevance_score DESC").
 		Limit(20) // 限制返回结果数量
 
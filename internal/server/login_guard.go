@@ -0,0 +1,231 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"NetherLink-server/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 登录失败原因
+const (
+	loginReasonWrongPassword = "wrong_password"
+	loginReasonLocked        = "locked"
+)
+
+// loginLimiter 全局登录防爆破计数器，在 NewHTTPServer 中按配置初始化
+var loginLimiter *loginGuard
+
+// loginGuard 按邮箱和IP统计登录失败次数，超过阈值后按指数退避锁定
+type loginGuard struct {
+	cfg     config.LoginGuardConfig
+	mu      sync.Mutex
+	entries map[string]*loginFailure // key: email:xxx 或 ip:xxx
+}
+
+type loginFailure struct {
+	Count       int
+	LastFailure time.Time
+	LockedUntil time.Time
+	Alerted     bool
+}
+
+func newLoginGuard(cfg config.LoginGuardConfig) *loginGuard {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 5
+	}
+	if cfg.IPMaxFailures <= 0 {
+		cfg.IPMaxFailures = 20
+	}
+	if cfg.BaseLockout <= 0 {
+		cfg.BaseLockout = time.Minute
+	}
+	if cfg.MaxLockout < cfg.BaseLockout {
+		cfg.MaxLockout = cfg.BaseLockout
+	}
+	if cfg.ResetAfter <= 0 {
+		cfg.ResetAfter = 24 * time.Hour
+	}
+
+	g := &loginGuard{
+		cfg:     cfg,
+		entries: make(map[string]*loginFailure),
+	}
+
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			g.sweep()
+		}
+	}()
+
+	return g
+}
+
+// emailGuardKey 邮箱统一转为小写，避免通过改变大小写绕过锁定计数
+func emailGuardKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipGuardKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockedFor 返回邮箱或IP仍需等待的锁定时长，0表示未锁定
+func (g *loginGuard) lockedFor(email, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range []string{emailGuardKey(email), ipGuardKey(ip)} {
+		if entry, ok := g.entries[key]; ok && entry.LockedUntil.After(now) {
+			if d := entry.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// fail 记录一次失败，返回该邮箱当前的连续失败次数，以及是否需要发送提醒邮件
+func (g *loginGuard) fail(email, ip string) (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	emailEntry := g.record(emailGuardKey(email), g.cfg.MaxFailures, now)
+	g.record(ipGuardKey(ip), g.cfg.IPMaxFailures, now)
+
+	alert := false
+	if g.cfg.AlertThreshold > 0 && emailEntry.Count >= g.cfg.AlertThreshold && !emailEntry.Alerted {
+		emailEntry.Alerted = true
+		alert = true
+	}
+	return emailEntry.Count, alert
+}
+
+func (g *loginGuard) record(key string, maxFailures int, now time.Time) *loginFailure {
+	entry, ok := g.entries[key]
+	if !ok || now.Sub(entry.LastFailure) > g.cfg.ResetAfter {
+		entry = &loginFailure{}
+		g.entries[key] = entry
+	}
+	entry.Count++
+	entry.LastFailure = now
+
+	if entry.Count >= maxFailures {
+		// 达到阈值后每多失败一次，锁定时长翻倍
+		lockout := g.cfg.BaseLockout
+		for i := maxFailures; i < entry.Count && lockout < g.cfg.MaxLockout; i++ {
+			lockout *= 2
+		}
+		if lockout > g.cfg.MaxLockout {
+			lockout = g.cfg.MaxLockout
+		}
+		entry.LockedUntil = now.Add(lockout)
+	}
+	return entry
+}
+
+// reset 登录成功后清除该邮箱的失败计数
+func (g *loginGuard) reset(email string) {
+	g.mu.Lock()
+	delete(g.entries, emailGuardKey(email))
+	g.mu.Unlock()
+}
+
+func (g *loginGuard) sweep() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range g.entries {
+		if now.Sub(entry.LastFailure) > g.cfg.ResetAfter && now.After(entry.LockedUntil) {
+			delete(g.entries, key)
+		}
+	}
+}
+
+// recordLogin 写入登录记录，写入失败只打日志，不影响登录流程
+func recordLogin(c *gin.Context, user *model.User, email string, success bool, reason string) {
+	db, err := getDB()
+	if err != nil {
+		log.Printf("记录登录历史失败: %v", err)
+		return
+	}
+
+	entry := model.LoginHistory{
+		Email:     email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if user != nil {
+		entry.UID = &user.UID
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("记录登录历史失败: %v", err)
+	}
+}
+
+// sendLoginAlert 异步给账号所有者发送登录失败提醒
+func sendLoginAlert(email, ip string, failures int) {
+	go func() {
+		emailCfg := config.GlobalConfig.Email
+		sender := utils.NewEmailSender(emailCfg.SMTPHost, emailCfg.SMTPPort, emailCfg.Sender, emailCfg.DisplayName, emailCfg.Password, emailCfg.UseSSL)
+		if err := sender.Send(email, "NetherLink 登录安全提醒", utils.GetLoginAlertEmailTemplate(ip, failures, time.Now())); err != nil {
+			log.Printf("发送登录提醒邮件失败: %v", err)
+		}
+	}()
+}
+
+// getLoginHistoryHandler 查看自己账号的登录记录
+func getLoginHistoryHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var total int64
+	var history []model.LoginHistory
+	query := db.Model(&model.LoginHistory{}).Where("uid = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取登录记录失败"})
+		return
+	}
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取登录记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+		"total":   total,
+		"page":    page,
+	})
+}
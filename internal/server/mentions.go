@@ -0,0 +1,211 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"gorm.io/gorm"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxMentionsPerText = 20
+	// @all 通知全体群成员，仅群主和管理员可用
+	mentionAllHandle = "all"
+	excerptLength    = 100
+)
+
+// 前面是字母数字、点或@时视为邮箱等普通文本，不当作提及
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@])@([A-Za-z0-9_]{3,20})`)
+
+// MentionedUser 被提及用户，随消息 Extra 和接口响应返回
+type MentionedUser struct {
+	UID  string `json:"uid"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// MentionNotification 被@时推送的 mention 事件
+type MentionNotification struct {
+	SourceType string `json:"source_type"` // post、comment、group_message
+	SourceID   int64  `json:"source_id"`
+	PostID     int64  `json:"post_id,omitempty"`
+	GroupID    int    `json:"group_id,omitempty"`
+	FromUID    string `json:"from_uid"`
+	FromName   string `json:"from_name"`
+	FromAvatar string `json:"from_avatar"`
+	Content    string `json:"content"` // 内容摘要
+	MentionAll bool   `json:"mention_all"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// parseMentionHandles 解析文本中的@用户ID，返回去重后的ID以及是否包含@all
+func parseMentionHandles(texts ...string) ([]string, bool) {
+	var handles []string
+	all := false
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+			handle := strings.ToLower(m[1])
+			if handle == mentionAllHandle {
+				all = true
+				continue
+			}
+			if !seen[handle] && len(handles) < maxMentionsPerText {
+				seen[handle] = true
+				handles = append(handles, handle)
+			}
+		}
+	}
+	return handles, all
+}
+
+// resolveMentions 把@的用户ID解析为用户，不存在、待注销的用户和自己会被忽略
+func resolveMentions(db *gorm.DB, fromUID string, handles []string) ([]MentionedUser, error) {
+	users := []MentionedUser{}
+	if len(handles) == 0 {
+		return users, nil
+	}
+	err := db.Model(&model.User{}).
+		Select("uid, id, name").
+		Where("id IN ? AND uid != ? AND delete_at IS NULL", handles, fromUID).
+		Scan(&users).Error
+	return users, err
+}
+
+// syncMentions 保存来源中的提及：新增本次出现的，删除已不再出现的，返回新被提及的用户
+func syncMentions(db *gorm.DB, sourceType string, sourceID int64, fromUID string, uids []string) ([]string, error) {
+	var existing []string
+	if err := db.Model(&model.Mention{}).
+		Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Pluck("mentioned_uid", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	had := make(map[string]bool, len(existing))
+	for _, uid := range existing {
+		had[uid] = true
+	}
+	keep := make(map[string]bool, len(uids))
+	var added []string
+	var rows []model.Mention
+	now := time.Now()
+	for _, uid := range uids {
+		keep[uid] = true
+		if !had[uid] {
+			added = append(added, uid)
+			rows = append(rows, model.Mention{
+				SourceType:   sourceType,
+				SourceID:     sourceID,
+				MentionedUID: uid,
+				FromUID:      fromUID,
+				CreatedAt:    now,
+			})
+		}
+	}
+	var removed []string
+	for _, uid := range existing {
+		if !keep[uid] {
+			removed = append(removed, uid)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(removed) > 0 {
+			if err := tx.Where("source_type = ? AND source_id = ? AND mentioned_uid IN ?", sourceType, sourceID, removed).
+				Delete(&model.Mention{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+	return added, err
+}
+
+// deleteMentions 删除来源下的全部提及记录
+func deleteMentions(tx *gorm.DB, sourceType string, sourceIDs []int64) error {
+	if len(sourceIDs) == 0 {
+		return nil
+	}
+	return tx.Where("source_type = ? AND source_id IN ?", sourceType, sourceIDs).Delete(&model.Mention{}).Error
+}
+
+// contentExcerpt 截取内容摘要用于推送
+func contentExcerpt(text string) string {
+	if utf8.RuneCountInString(text) <= excerptLength {
+		return text
+	}
+	return string([]rune(text)[:excerptLength]) + "..."
+}
+
+// newMentionNotification 填充提及者信息，查询失败时只缺少昵称和头像
+func newMentionNotification(db *gorm.DB, sourceType string, sourceID int64, fromUID, content string) MentionNotification {
+	notification := MentionNotification{
+		SourceType: sourceType,
+		SourceID:   sourceID,
+		FromUID:    fromUID,
+		Content:    contentExcerpt(content),
+		CreatedAt:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+	var from model.User
+	if err := db.Select("name, avatar_url").Where("uid = ?", fromUID).First(&from).Error; err == nil {
+		notification.FromName = from.Name
+		notification.FromAvatar = from.AvatarURL
+	}
+	return notification
+}
+
+// notifyPostMentions 解析动态中的@并通知能看到这条动态的新被提及用户
+func (s *HTTPServer) notifyPostMentions(db *gorm.DB, post *model.Post) []MentionedUser {
+	handles, _ := parseMentionHandles(post.Title, post.Content)
+	mentioned, added := s.syncVisibleMentions(db, post, model.MentionSourcePost, post.PostID, post.UserID, handles)
+	if len(added) > 0 {
+		notification := newMentionNotification(db, model.MentionSourcePost, post.PostID, post.UserID, post.Content)
+		notification.PostID = post.PostID
+		s.events.Publish(added, "mention", notification)
+	}
+	return mentioned
+}
+
+// notifyCommentMentions 解析评论中的@并通知能看到所属动态的新被提及用户
+func (s *HTTPServer) notifyCommentMentions(db *gorm.DB, post *model.Post, comment *model.Comment) []MentionedUser {
+	handles, _ := parseMentionHandles(comment.Content)
+	mentioned, added := s.syncVisibleMentions(db, post, model.MentionSourceComment, comment.CommentID, comment.UserID, handles)
+	if len(added) > 0 {
+		notification := newMentionNotification(db, model.MentionSourceComment, comment.CommentID, comment.UserID, comment.Content)
+		notification.PostID = post.PostID
+		s.events.Publish(added, "mention", notification)
+	}
+	return mentioned
+}
+
+// syncVisibleMentions 过滤掉看不到动态的用户后保存提及，提及失败不影响发布，只记录日志
+func (s *HTTPServer) syncVisibleMentions(db *gorm.DB, post *model.Post, sourceType string, sourceID int64, fromUID string, handles []string) ([]MentionedUser, []string) {
+	users, err := resolveMentions(db, fromUID, handles)
+	if err != nil {
+		log.Printf("解析提及失败: %v", err)
+		return []MentionedUser{}, nil
+	}
+
+	mentioned := make([]MentionedUser, 0, len(users))
+	uids := make([]string, 0, len(users))
+	for _, user := range users {
+		if visible, err := canViewPost(db, user.UID, post); err != nil || !visible {
+			continue
+		}
+		mentioned = append(mentioned, user)
+		uids = append(uids, user.UID)
+	}
+
+	added, err := syncMentions(db, sourceType, sourceID, fromUID, uids)
+	if err != nil {
+		log.Printf("保存提及失败: %v", err)
+		return mentioned, nil
+	}
+	return mentioned, added
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"NetherLink-server/pkg/utils"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 敏感词分类的处理方式
+const (
+	moderationActionBlock = "block"
+	moderationActionMask  = "mask"
+	moderationActionFlag  = "flag"
+
+	moderationMask = "***"
+)
+
+var errContentBlocked = errors.New("内容包含违规信息，请修改后再提交")
+
+// ModerationHit 命中的敏感词及其分类
+type ModerationHit struct {
+	Word     string `json:"word"`
+	Category string `json:"category"`
+}
+
+// contentModerator 敏感词过滤器，词库文件修改后自动重新加载
+type contentModerator struct {
+	mu            sync.RWMutex
+	matcher       *utils.WordMatcher
+	categories    map[string]string // 小写的词 -> 分类
+	actions       map[string]string
+	defaultAction string
+	path          string
+	modTime       time.Time
+}
+
+var moderator *contentModerator
+
+func newContentModerator(cfg config.ModerationConfig) *contentModerator {
+	m := &contentModerator{
+		actions:       make(map[string]string, len(cfg.Actions)),
+		defaultAction: normalizeModerationAction(cfg.DefaultAction, moderationActionMask),
+		path:          cfg.DictFile,
+	}
+	for category, action := range cfg.Actions {
+		m.actions[strings.ToLower(category)] = normalizeModerationAction(action, m.defaultAction)
+	}
+	if err := m.reloadIfChanged(); err != nil {
+		log.Printf("加载敏感词词库失败: %v", err)
+	}
+	return m
+}
+
+func normalizeModerationAction(action, fallback string) string {
+	switch action = strings.ToLower(strings.TrimSpace(action)); action {
+	case moderationActionBlock, moderationActionMask, moderationActionFlag:
+		return action
+	default:
+		return fallback
+	}
+}
+
+// reloadIfChanged 词库文件的修改时间变化后重新加载，解析失败时保留原词库
+func (m *contentModerator) reloadIfChanged() error {
+	if m.path == "" {
+		return nil
+	}
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return err
+	}
+	m.mu.RLock()
+	unchanged := info.ModTime().Equal(m.modTime)
+	m.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	words, categories, err := loadWordDict(m.path)
+	if err != nil {
+		return err
+	}
+	matcher := utils.NewWordMatcher(words)
+
+	m.mu.Lock()
+	m.matcher = matcher
+	m.categories = categories
+	m.modTime = info.ModTime()
+	m.mu.Unlock()
+	log.Printf("已加载敏感词词库，共%d个词", matcher.Len())
+	return nil
+}
+
+// loadWordDict 解析词库文件，[分类] 开始一个分类，# 开头为注释，分类之前的词归入 default
+func loadWordDict(path string) ([]string, map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	categories := make(map[string]string)
+	category := "default"
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			category = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		words = append(words, line)
+		categories[strings.ToLower(line)] = category
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return words, categories, nil
+}
+
+// startModerationReloader 定期检查词库文件是否修改
+func startModerationReloader(m *contentModerator, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := m.reloadIfChanged(); err != nil {
+				log.Printf("重新加载敏感词词库失败: %v", err)
+			}
+		}
+	}()
+}
+
+// check 过滤一段文本：命中 block 类返回错误，mask 类替换为***，flag 类原样放行并返回命中的词
+func (m *contentModerator) check(text string) (string, []ModerationHit, error) {
+	if m == nil || text == "" {
+		return text, nil, nil
+	}
+	m.mu.RLock()
+	matcher, categories := m.matcher, m.categories
+	m.mu.RUnlock()
+
+	matches := matcher.FindAll(text)
+	if len(matches) == 0 {
+		return text, nil, nil
+	}
+
+	var masked []utils.WordMatch
+	var flagged []ModerationHit
+	for _, match := range matches {
+		category := categories[strings.ToLower(match.Word)]
+		action, ok := m.actions[category]
+		if !ok {
+			action = m.defaultAction
+		}
+		switch action {
+		case moderationActionBlock:
+			return text, nil, errContentBlocked
+		case moderationActionMask:
+			masked = append(masked, match)
+		case moderationActionFlag:
+			flagged = append(flagged, ModerationHit{Word: match.Word, Category: category})
+		}
+	}
+	return maskMatches(text, masked), flagged, nil
+}
+
+// maskMatches 把命中的区间替换为***，重叠或相邻的区间合并后只替换一次
+func maskMatches(text string, matches []utils.WordMatch) string {
+	if len(matches) == 0 {
+		return text
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+
+	runes := []rune(text)
+	var b strings.Builder
+	pos := 0
+	for i := 0; i < len(matches); {
+		start, end := matches[i].Start, matches[i].End
+		for i++; i < len(matches) && matches[i].Start <= end; i++ {
+			if matches[i].End > end {
+				end = matches[i].End
+			}
+		}
+		b.WriteString(string(runes[pos:start]))
+		b.WriteString(moderationMask)
+		pos = end
+	}
+	b.WriteString(string(runes[pos:]))
+	return b.String()
+}
+
+// moderateFields 依次过滤多个字段并原地替换，任一字段被拒绝时返回错误
+func moderateFields(fields ...*string) ([]ModerationHit, error) {
+	var hits []ModerationHit
+	for _, field := range fields {
+		text, fieldHits, err := moderator.check(*field)
+		if err != nil {
+			return nil, err
+		}
+		*field = text
+		hits = append(hits, fieldHits...)
+	}
+	return hits, nil
+}
+
+// flagForReview 内容保存后记录待审核，没有命中 flag 类敏感词时不做任何事
+func flagForReview(db *gorm.DB, scene, sourceID, uid, content string, hits []ModerationHit) {
+	if len(hits) == 0 {
+		return
+	}
+	data, err := json.Marshal(hits)
+	if err != nil {
+		log.Printf("记录待审核内容失败: %v", err)
+		return
+	}
+	flag := model.ModerationFlag{
+		Scene:     scene,
+		SourceID:  sourceID,
+		UID:       uid,
+		Content:   content,
+		Hits:      string(data),
+		Status:    model.ModerationFlagPending,
+		CreatedAt: time.Now(),
+	}
+	if err := db.Create(&flag).Error; err != nil {
+		log.Printf("记录待审核内容失败: %v", err)
+	}
+}
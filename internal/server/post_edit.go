@@ -0,0 +1,401 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type updatePostRequest struct {
+	Title       *string  `json:"title"`
+	Content     *string  `json:"content"`
+	Visibility  *string  `json:"visibility"`
+	VisibleUIDs []string `json:"visible_uids"`
+}
+
+type updateCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// updatePostHandler 作者编辑动态的标题、内容和可见范围
+func (s *HTTPServer) updatePostHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "无效的帖子ID"})
+		return
+	}
+
+	var req updatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "无效的请求参数"})
+		return
+	}
+
+	var fields []*string
+	if req.Title != nil {
+		fields = append(fields, req.Title)
+	}
+	if req.Content != nil {
+		fields = append(fields, req.Content)
+	}
+	hits, err := moderateFields(fields...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		if strings.TrimSpace(*req.Title) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "标题不能为空"})
+			return
+		}
+		updates["title"] = *req.Title
+	}
+	if req.Content != nil {
+		if strings.TrimSpace(*req.Content) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "内容不能为空"})
+			return
+		}
+		updates["content"] = *req.Content
+	}
+	if len(updates) == 0 && req.Visibility == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "没有需要修改的内容"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+		return
+	}
+
+	post, ok := loadOwnPost(c, db, postID, userID)
+	if !ok {
+		return
+	}
+
+	var visibleUIDs []string
+	if req.Visibility != nil {
+		var visibility string
+		visibility, visibleUIDs, err = normalizeVisibility(db, userID, *req.Visibility, req.VisibleUIDs)
+		if err != nil {
+			if err == errInvalidVisibility || err == errVisibleUsers {
+				c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询好友失败"})
+			}
+			return
+		}
+		updates["visibility"] = visibility
+	}
+
+	// 只修改可见范围不算编辑内容
+	now := time.Now()
+	if req.Title != nil || req.Content != nil {
+		updates["edited_at"] = now
+		post.EditedAt = &now
+	}
+	updates["updated_at"] = now
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(post).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 标题或内容变化后重新解析话题
+		if req.Title != nil || req.Content != nil {
+			if req.Title != nil {
+				post.Title = *req.Title
+			}
+			if req.Content != nil {
+				post.Content = *req.Content
+			}
+			if err := indexPostTags(tx, post); err != nil {
+				return err
+			}
+		}
+		if req.Visibility == nil {
+			return nil
+		}
+		return savePostVisibleUsers(tx, post.PostID, visibleUIDs)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "编辑帖子失败"})
+		return
+	}
+	flagForReview(db, model.ModerationScenePost, strconv.FormatInt(post.PostID, 10), userID, post.Title+"\n"+post.Content, hits)
+	if v, ok := updates["visibility"].(string); ok {
+		post.Visibility = v
+	}
+	// 可见范围变化或内容变化都可能影响能收到提及的人
+	mentions := s.notifyPostMentions(db, post)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": gin.H{
+			"post_id":    post.PostID,
+			"title":      post.Title,
+			"content":    post.Content,
+			"visibility": post.Visibility,
+			"mentions":   mentions,
+			"edited_at":  formatOptionalTime(post.EditedAt),
+		},
+	})
+}
+
+// deletePostHandler 作者删除动态，评论一并软删除，点赞和图片直接清除
+func deletePostHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "无效的帖子ID"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+		return
+	}
+
+	post, ok := loadOwnPost(c, db, postID, userID)
+	if !ok {
+		return
+	}
+
+	if err := removePost(db, post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "删除帖子失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "帖子已删除"})
+}
+
+// removePost 删除动态，评论一并软删除，点赞、图片等关联数据直接清除；作者删除和管理员处理举报共用
+func removePost(db *gorm.DB, post *model.Post) error {
+	var images []model.PostImage
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		images, err = deletePostRecords(tx, post)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	removePostFiles(post, images)
+	return nil
+}
+
+// deletePostRecords 在事务内删除动态及关联数据，返回需要在提交后删除文件的图片
+func deletePostRecords(tx *gorm.DB, post *model.Post) ([]model.PostImage, error) {
+	var images []model.PostImage
+	if err := tx.Where("post_id = ?", post.PostID).Find(&images).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.PostID).Delete(&model.PostImage{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.PostID).Delete(&model.PostLike{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.PostID).Delete(&model.PostVisibleUser{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.PostID).Delete(&model.PostTag{}).Error; err != nil {
+		return nil, err
+	}
+	var commentIDs []int64
+	if err := tx.Model(&model.Comment{}).Where("post_id = ?", post.PostID).Pluck("comment_id", &commentIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := deleteMentions(tx, model.MentionSourceComment, commentIDs); err != nil {
+		return nil, err
+	}
+	if err := deleteMentions(tx, model.MentionSourcePost, []int64{post.PostID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.PostID).Delete(&model.Comment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Delete(post).Error; err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// removePostFiles 删除动态的图片文件，需在数据库事务提交后调用
+func removePostFiles(post *model.Post, images []model.PostImage) {
+	removePostImageFiles(images)
+	if post.ImageURL != "" {
+		if err := os.Remove(filepath.Join(postUploadDir, path.Base(post.ImageURL))); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除动态图片失败: %v", err)
+		}
+	}
+}
+
+// loadOwnPost 查询帖子并确认当前用户是作者，失败时已写入响应
+func loadOwnPost(c *gin.Context, db *gorm.DB, postID int64, userID string) (*model.Post, bool) {
+	var post model.Post
+	if err := db.First(&post, postID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"code": -1, "message": "帖子不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询帖子失败"})
+		}
+		return nil, false
+	}
+	if post.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "message": "只能操作自己的帖子"})
+		return nil, false
+	}
+	return &post, true
+}
+
+// loadComment 查询帖子下的评论，失败时已写入响应
+func loadComment(c *gin.Context, db *gorm.DB) (*model.Comment, bool) {
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的帖子ID"})
+		return nil, false
+	}
+	commentID, err := strconv.ParseInt(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return nil, false
+	}
+
+	var comment model.Comment
+	if err := db.Where("comment_id = ? AND post_id = ?", commentID, postID).First(&comment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询评论失败"})
+		}
+		return nil, false
+	}
+	return &comment, true
+}
+
+// updateCommentHandler 评论者编辑自己的评论
+func (s *HTTPServer) updateCommentHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req updateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "评论内容不能为空"})
+		return
+	}
+	hits, err := moderateFields(&req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	comment, ok := loadComment(c, db)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能编辑自己的评论"})
+		return
+	}
+
+	now := time.Now()
+	if err := db.Model(comment).Updates(map[string]interface{}{
+		"content":   req.Content,
+		"edited_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "编辑评论失败"})
+		return
+	}
+	comment.Content = req.Content
+	flagForReview(db, model.ModerationSceneComment, strconv.FormatInt(comment.CommentID, 10), userID, comment.Content, hits)
+
+	mentions := []MentionedUser{}
+	var post model.Post
+	if err := db.First(&post, comment.PostID).Error; err == nil {
+		mentions = s.notifyCommentMentions(db, &post, comment)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comment_id": comment.CommentID,
+		"post_id":    comment.PostID,
+		"content":    comment.Content,
+		"mentions":   mentions,
+		"edited_at":  now.Format("2006-01-02 15:04:05"),
+	})
+}
+
+// deleteCommentHandler 删除评论，评论者本人和帖子作者都可以删除
+func deleteCommentHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	comment, ok := loadComment(c, db)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID {
+		var post model.Post
+		if err := db.First(&post, comment.PostID).Error; err != nil || post.UserID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权删除该评论"})
+			return
+		}
+	}
+
+	if err := removeComment(db, comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评论失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "评论已删除"})
+}
+
+// removeComment 软删除评论，一级评论删除后如果还有回复，在列表中显示为占位
+func removeComment(db *gorm.DB, comment *model.Comment) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(comment).Error; err != nil {
+			return err
+		}
+		if err := deleteMentions(tx, model.MentionSourceComment, []int64{comment.CommentID}); err != nil {
+			return err
+		}
+		if comment.ParentCommentID == nil {
+			return nil
+		}
+		return tx.Model(&model.Comment{}).Unscoped().Where("comment_id = ?", *comment.ParentCommentID).
+			Update("reply_count", gorm.Expr("GREATEST(reply_count - 1, 0)")).Error
+	})
+}
+
+// formatOptionalTime 格式化可能为空的时间，为空时返回nil
+func formatOptionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
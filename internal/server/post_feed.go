@@ -0,0 +1,209 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFeedLimit = 20
+	maxFeedLimit     = 50
+)
+
+var errInvalidCursor = errors.New("无效的分页游标")
+
+// feedCursor 动态列表游标，按 created_at、post_id 倒序翻页
+type feedCursor struct {
+	CreatedAt time.Time
+	PostID    int64
+}
+
+// feedRow 动态列表查询结果
+type feedRow struct {
+	PostID     int64     `gorm:"column:post_id"`
+	Title      string    `gorm:"column:title"`
+	UserID     string    `gorm:"column:user_id"`
+	UserName   string    `gorm:"column:name"`
+	UserAvatar string    `gorm:"column:avatar_url"`
+	ImageURL   string    `gorm:"column:image_url"`
+	RepostOfID *int64    `gorm:"column:repost_of_id"`
+	ShareCount int       `gorm:"column:share_count"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+// parseFeedCursor 解析形如 "1700000000_123" 的游标，空字符串表示第一页
+func parseFeedCursor(raw string) (*feedCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(raw, "_", 2)
+	if len(parts) != 2 {
+		return nil, errInvalidCursor
+	}
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	postID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &feedCursor{CreatedAt: time.Unix(sec, 0), PostID: postID}, nil
+}
+
+func (fc feedCursor) String() string {
+	return fmt.Sprintf("%d_%d", fc.CreatedAt.Unix(), fc.PostID)
+}
+
+// parseFeedLimit 读取每页条数，超出范围时使用默认值
+func parseFeedLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultFeedLimit)))
+	if err != nil || limit < 1 || limit > maxFeedLimit {
+		return defaultFeedLimit
+	}
+	return limit
+}
+
+// queryFeedPage 在query基础上按游标取一页动态，多取一条用于判断是否还有下一页
+func queryFeedPage(query *gorm.DB, cursor *feedCursor, limit int) ([]feedRow, *feedCursor, error) {
+	query = query.
+		Select("posts.post_id, posts.title, posts.user_id, posts.image_url, posts.repost_of_id, posts.share_count, users.name, users.avatar_url, posts.created_at").
+		Joins("LEFT JOIN users ON posts.user_id = users.uid").
+		Where("posts.deleted_at IS NULL")
+	if cursor != nil {
+		query = query.Where("posts.created_at < ? OR (posts.created_at = ? AND posts.post_id < ?)",
+			cursor.CreatedAt, cursor.CreatedAt, cursor.PostID)
+	}
+
+	var rows []feedRow
+	if err := query.Order("posts.created_at DESC, posts.post_id DESC").
+		Limit(limit + 1).
+		Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var next *feedCursor
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = &feedCursor{CreatedAt: last.CreatedAt, PostID: last.PostID}
+	}
+	return rows, next, nil
+}
+
+// buildPostPreviews 批量统计点赞数、评论数和当前用户的点赞状态，避免逐条查询
+func buildPostPreviews(db *gorm.DB, viewerUID string, rows []feedRow) ([]model.PostPreview, error) {
+	previews := make([]model.PostPreview, 0, len(rows))
+	if len(rows) == 0 {
+		return previews, nil
+	}
+
+	postIDs := make([]int64, 0, len(rows))
+	var repostIDs []int64
+	for _, row := range rows {
+		postIDs = append(postIDs, row.PostID)
+		if row.RepostOfID != nil {
+			repostIDs = append(repostIDs, *row.RepostOfID)
+		}
+	}
+
+	type countRow struct {
+		PostID int64 `gorm:"column:post_id"`
+		Total  int64 `gorm:"column:total"`
+	}
+
+	var likeCounts []countRow
+	if err := db.Model(&model.PostLike{}).
+		Select("post_id, COUNT(*) AS total").
+		Where("post_id IN ?", postIDs).
+		Group("post_id").
+		Scan(&likeCounts).Error; err != nil {
+		return nil, err
+	}
+
+	var commentCounts []countRow
+	if err := db.Model(&model.Comment{}).
+		Select("post_id, COUNT(*) AS total").
+		Where("post_id IN ?", postIDs).
+		Group("post_id").
+		Scan(&commentCounts).Error; err != nil {
+		return nil, err
+	}
+
+	var likedIDs []int64
+	if err := db.Model(&model.PostLike{}).
+		Where("post_id IN ? AND user_id = ?", postIDs, viewerUID).
+		Pluck("post_id", &likedIDs).Error; err != nil {
+		return nil, err
+	}
+
+	firstImages, err := firstPostImages(db, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 转发的原动态按当前内容和可见范围生成卡片
+	cards, err := loadPostCards(db, viewerUID, repostIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	likes := make(map[int64]int64, len(likeCounts))
+	for _, lc := range likeCounts {
+		likes[lc.PostID] = lc.Total
+	}
+	comments := make(map[int64]int64, len(commentCounts))
+	for _, cc := range commentCounts {
+		comments[cc.PostID] = cc.Total
+	}
+	liked := make(map[int64]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	for _, row := range rows {
+		row := row
+		preview := model.PostPreview{
+			PostID:        row.PostID,
+			Title:         row.Title,
+			UserID:        row.UserID,
+			UserName:      row.UserName,
+			UserAvatar:    &row.UserAvatar,
+			LikesCount:    likes[row.PostID],
+			CommentsCount: comments[row.PostID],
+			IsLiked:       liked[row.PostID],
+			ShareCount:    row.ShareCount,
+			CreatedAt:     row.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if row.RepostOfID != nil {
+			preview.RepostOf = cards[*row.RepostOfID]
+		}
+		if first, ok := firstImages[row.PostID]; ok {
+			preview.FirstImage = &first
+		} else if row.ImageURL != "" {
+			// 旧版动态的单张图片
+			preview.FirstImage = &row.ImageURL
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+// feedResponse 组装分页响应，没有下一页时 next_cursor 为空
+func feedResponse(previews []model.PostPreview, next *feedCursor) gin.H {
+	resp := gin.H{
+		"posts":       previews,
+		"has_more":    next != nil,
+		"next_cursor": "",
+	}
+	if next != nil {
+		resp["next_cursor"] = next.String()
+	}
+	return resp
+}
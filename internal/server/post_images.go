@@ -0,0 +1,157 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"NetherLink-server/pkg/utils"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"mime/multipart"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	postUploadDir = "uploads/posts"
+	maxPostImages = 9
+	// 单张图片最大像素数，防止超大图片解码占满内存
+	maxPostImagePixels   = 50000000
+	defaultThumbnailSize = 360
+)
+
+var (
+	errTooManyPostImages = fmt.Errorf("每条动态最多%d张图片", maxPostImages)
+	errPostImageType     = errors.New("只能上传图片文件")
+	errPostImageTooLarge = errors.New("图片尺寸过大")
+)
+
+func postImageURL(filename string) string {
+	return fmt.Sprintf("%s/uploads/posts/%s", config.GlobalConfig.Server.HTTP.BaseURL, filename)
+}
+
+// validatePostImages 在创建动态前检查图片数量和格式
+func validatePostImages(files []*multipart.FileHeader) error {
+	if len(files) > maxPostImages {
+		return errTooManyPostImages
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Header.Get("Content-Type"), "image/") {
+			return errPostImageType
+		}
+		ext := strings.ToLower(filepath.Ext(file.Filename))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" {
+			return errPostImageType
+		}
+	}
+	return nil
+}
+
+// savePostImages 在创建动态前保存图片并生成缩略图，出错时清理已保存的文件；返回的记录需在写入前设置 PostID
+func savePostImages(c *gin.Context, userID string, files []*multipart.FileHeader) ([]model.PostImage, error) {
+	if err := os.MkdirAll(postUploadDir, 0755); err != nil {
+		return nil, errors.New("创建目录失败")
+	}
+
+	thumbSize := config.GlobalConfig.Image.ThumbnailSize
+	if thumbSize <= 0 {
+		thumbSize = defaultThumbnailSize
+	}
+
+	stamp := time.Now().UnixNano()
+	images := make([]model.PostImage, 0, len(files))
+	for i, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Filename))
+		filename := fmt.Sprintf("post_%s_%d_%d%s", userID, stamp, i, ext)
+		thumbName := fmt.Sprintf("post_%s_%d_%d_thumb.jpg", userID, stamp, i)
+		savePath := filepath.Join(postUploadDir, filename)
+
+		image := model.PostImage{
+			Position:  i,
+			URL:       postImageURL(filename),
+			CreatedAt: time.Now(),
+		}
+		if err := c.SaveUploadedFile(file, savePath); err != nil {
+			removePostImageFiles(images)
+			return nil, errors.New("保存图片失败")
+		}
+		// 先加入列表，后续步骤失败时一并清理
+		images = append(images, image)
+
+		width, height, err := utils.DecodeImageSize(savePath)
+		if err != nil {
+			removePostImageFiles(images)
+			return nil, errPostImageType
+		}
+		if width*height > maxPostImagePixels {
+			removePostImageFiles(images)
+			return nil, errPostImageTooLarge
+		}
+		if err := utils.GenerateThumbnail(savePath, filepath.Join(postUploadDir, thumbName), thumbSize); err != nil {
+			removePostImageFiles(images)
+			return nil, errors.New("生成缩略图失败")
+		}
+
+		images[i].Width = width
+		images[i].Height = height
+		images[i].ThumbnailURL = postImageURL(thumbName)
+	}
+	return images, nil
+}
+
+// removePostImageFiles 删除动态图片及缩略图文件
+func removePostImageFiles(images []model.PostImage) {
+	for _, image := range images {
+		for _, url := range []string{image.URL, image.ThumbnailURL} {
+			if url == "" {
+				continue
+			}
+			if err := os.Remove(filepath.Join(postUploadDir, path.Base(url))); err != nil && !os.IsNotExist(err) {
+				log.Printf("删除动态图片失败: %v", err)
+			}
+		}
+	}
+}
+
+// firstPostImages 批量获取动态的首张图片，列表页使用缩略图
+func firstPostImages(db *gorm.DB, postIDs []int64) (map[int64]string, error) {
+	var images []model.PostImage
+	if err := db.Where("post_id IN ? AND position = 0", postIDs).Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	first := make(map[int64]string, len(images))
+	for _, image := range images {
+		if image.ThumbnailURL != "" {
+			first[image.PostID] = image.ThumbnailURL
+		} else {
+			first[image.PostID] = image.URL
+		}
+	}
+	return first, nil
+}
+
+// firstImageURL 返回第一张图片的原图地址，没有图片时为空
+func firstImageURL(images []model.PostImage) string {
+	if len(images) == 0 {
+		return ""
+	}
+	return images[0].URL
+}
+
+// fillLegacyPostImage 旧版动态只有posts.image_url一张图，转换为图片列表返回
+func fillLegacyPostImage(post *model.Post) {
+	if len(post.Images) == 0 && post.ImageURL != "" {
+		post.Images = []model.PostImage{{
+			PostID:       post.PostID,
+			URL:          post.ImageURL,
+			ThumbnailURL: post.ImageURL,
+			CreatedAt:    post.CreatedAt,
+		}}
+	}
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 聊天中分享动态的消息类型，Extra 中带 post_id
+	messageTypePostShare = "post_share"
+	maxPostCardIDs       = 50
+)
+
+var errSharePost = errors.New("分享的动态不存在或无权查看")
+
+type repostRequest struct {
+	Content     string   `json:"content"`
+	Visibility  string   `json:"visibility"`
+	VisibleUIDs []string `json:"visible_uids"`
+}
+
+// loadPostCards 按原动态的最新内容生成卡片，已删除或当前用户看不到的只返回状态
+func loadPostCards(db *gorm.DB, viewerUID string, postIDs []int64) (map[int64]*model.PostCard, error) {
+	cards := make(map[int64]*model.PostCard, len(postIDs))
+	if len(postIDs) == 0 {
+		return cards, nil
+	}
+	for _, id := range postIDs {
+		cards[id] = &model.PostCard{PostID: id, Status: model.PostCardDeleted}
+	}
+
+	var posts []model.Post
+	if err := db.Where("post_id IN ?", postIDs).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	var visible []int64
+	var uids []string
+	for i := range posts {
+		ok, err := canViewPost(db, viewerUID, &posts[i])
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			cards[posts[i].PostID].Status = model.PostCardUnavailable
+			continue
+		}
+		visible = append(visible, posts[i].PostID)
+		uids = append(uids, posts[i].UserID)
+	}
+	if len(visible) == 0 {
+		return cards, nil
+	}
+
+	var users []model.User
+	if err := db.Select("uid, name").Where("uid IN ?", uids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, user := range users {
+		names[user.UID] = user.Name
+	}
+	firstImages, err := firstPostImages(db, visible)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range posts {
+		card := cards[post.PostID]
+		if card.Status == model.PostCardUnavailable {
+			continue
+		}
+		card.Status = model.PostCardAvailable
+		card.Title = post.Title
+		card.Content = contentExcerpt(post.Content)
+		card.UserID = post.UserID
+		card.UserName = names[post.UserID]
+		if first, ok := firstImages[post.PostID]; ok {
+			card.FirstImage = first
+		} else {
+			card.FirstImage = post.ImageURL
+		}
+	}
+	return cards, nil
+}
+
+// incrementShareCount 原动态的分享次数加一
+func incrementShareCount(db *gorm.DB, postID int64) error {
+	return db.Model(&model.Post{}).Where("post_id = ?", postID).
+		Update("share_count", gorm.Expr("share_count + 1")).Error
+}
+
+// preparePostShare 校验聊天中分享的动态，规范化 Extra 并返回动态ID
+func preparePostShare(db *gorm.DB, uid string, chatPayload *ChatPayload) (int64, error) {
+	extra := map[string]interface{}{}
+	if chatPayload.Extra != "" {
+		if err := json.Unmarshal([]byte(chatPayload.Extra), &extra); err != nil {
+			return 0, errors.New("无效的消息附加信息")
+		}
+	}
+	rawID, ok := extra["post_id"].(float64)
+	if !ok || rawID <= 0 {
+		return 0, errors.New("缺少分享的动态ID")
+	}
+	postID := int64(rawID)
+
+	var post model.Post
+	if err := db.First(&post, postID).Error; err != nil {
+		return 0, errSharePost
+	}
+	if visible, err := canViewPost(db, uid, &post); err != nil || !visible {
+		return 0, errSharePost
+	}
+
+	// 卡片内容由接收方按 post_id 实时获取，这里只保留ID
+	extra["post_id"] = postID
+	data, err := json.Marshal(extra)
+	if err != nil {
+		return 0, errors.New("生成消息失败")
+	}
+	chatPayload.Extra = string(data)
+	if strings.TrimSpace(chatPayload.Content) == "" {
+		chatPayload.Content = "[动态] " + post.Title
+	}
+	return postID, nil
+}
+
+// getPostCardsHandler 批量获取动态卡片，供聊天中的分享消息实时展示
+func getPostCardsHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var ids []int64
+	for _, raw := range strings.Split(c.Query("ids"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的帖子ID"})
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 || len(ids) > maxPostCardIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "一次最多查询50条动态"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	cards, err := loadPostCards(db, userID, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取动态失败"})
+		return
+	}
+	result := make([]*model.PostCard, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, cards[id])
+	}
+	c.JSON(http.StatusOK, gin.H{"cards": result})
+}
+
+// repostHandler 转发动态到自己的动态列表，转发的转发指向最初的原动态
+func (s *HTTPServer) repostHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "无效的帖子ID"})
+		return
+	}
+
+	var req repostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "无效的请求参数"})
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		req.Content = "转发动态"
+	}
+	hits, err := moderateFields(&req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+		return
+	}
+
+	var source model.Post
+	if err := db.First(&source, postID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"code": -1, "message": "帖子不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询帖子失败"})
+		}
+		return
+	}
+	if visible, err := canViewPost(db, userID, &source); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询帖子失败"})
+		return
+	} else if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "message": errPostsHidden.Error()})
+		return
+	}
+	originID := source.PostID
+	if source.RepostOfID != nil {
+		originID = *source.RepostOfID
+	}
+
+	visibility, visibleUIDs, err := normalizeVisibility(db, userID, req.Visibility, req.VisibleUIDs)
+	if err != nil {
+		if err == errInvalidVisibility || err == errVisibleUsers {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询好友失败"})
+		}
+		return
+	}
+
+	now := time.Now()
+	post := &model.Post{
+		UserID:     userID,
+		Content:    req.Content,
+		Visibility: visibility,
+		RepostOfID: &originID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if err := indexPostTags(tx, post); err != nil {
+			return err
+		}
+		if err := savePostVisibleUsers(tx, post.PostID, visibleUIDs); err != nil {
+			return err
+		}
+		return incrementShareCount(tx, originID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "转发失败"})
+		return
+	}
+
+	flagForReview(db, model.ModerationScenePost, strconv.FormatInt(post.PostID, 10), userID, post.Content, hits)
+	mentions := s.notifyPostMentions(db, post)
+	cards, err := loadPostCards(db, userID, []int64{originID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取原动态失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": gin.H{
+			"post_id":    post.PostID,
+			"content":    post.Content,
+			"user_id":    post.UserID,
+			"visibility": post.Visibility,
+			"repost_of":  cards[originID],
+			"mentions":   mentions,
+			"created_at": post.CreatedAt.Format("2006-01-02 15:04:05"),
+		},
+	})
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTagLength   = 32
+	maxTagsPerPost = 10
+
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+	defaultTrendingLimit  = 20
+	maxTrendingLimit      = 50
+)
+
+var (
+	// #话题# 形式，两个#之间不含空白
+	pairedTagPattern = regexp.MustCompile(`#([^#\s]+)#`)
+	// #tag 形式，遇到标点或空白结束
+	inlineTagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+)
+
+// TrendingTag 热门话题统计结果
+type TrendingTag struct {
+	TagID     int64  `gorm:"column:tag_id" json:"tag_id"`
+	Name      string `gorm:"column:name" json:"name"`
+	PostCount int64  `gorm:"column:post_count" json:"post_count"`
+}
+
+// normalizeTag 去除首尾空白并转为小写，长度不合法时返回空字符串
+func normalizeTag(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || utf8.RuneCountInString(name) > maxTagLength {
+		return ""
+	}
+	return name
+}
+
+// extractTags 从文本中解析 #话题# 和 #tag，去重后最多保留10个
+func extractTags(texts ...string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name = normalizeTag(name); name != "" && !seen[name] && len(tags) < maxTagsPerPost {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+
+	for _, text := range texts {
+		for _, m := range pairedTagPattern.FindAllStringSubmatch(text, -1) {
+			add(m[1])
+		}
+		// 去掉已匹配的 #话题#，避免再被当作 #tag 解析
+		rest := pairedTagPattern.ReplaceAllString(text, " ")
+		for _, m := range inlineTagPattern.FindAllStringSubmatch(rest, -1) {
+			add(m[1])
+		}
+	}
+	return tags
+}
+
+// indexPostTags 根据标题和内容重建动态的话题关联
+func indexPostTags(tx *gorm.DB, post *model.Post) error {
+	if err := tx.Where("post_id = ?", post.PostID).Delete(&model.PostTag{}).Error; err != nil {
+		return err
+	}
+
+	names := extractTags(post.Title, post.Content)
+	if len(names) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	tags := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, model.Tag{Name: name, CreatedAt: now})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+		return err
+	}
+
+	var tagIDs []int64
+	if err := tx.Model(&model.Tag{}).Where("name IN ?", names).Pluck("tag_id", &tagIDs).Error; err != nil {
+		return err
+	}
+	rows := make([]model.PostTag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		rows = append(rows, model.PostTag{PostID: post.PostID, TagID: tagID, CreatedAt: post.CreatedAt})
+	}
+	return tx.Create(&rows).Error
+}
+
+// getTagPostsHandler 获取话题下的动态，分页方式同动态列表
+func getTagPostsHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	name := normalizeTag(c.Param("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "无效的话题"})
+		return
+	}
+	cursor, err := parseFeedCursor(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+	limit := parseFeedLimit(c)
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+		return
+	}
+
+	var tag model.Tag
+	if err := db.Where("name = ?", name).First(&tag).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"code": -1, "message": "话题不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "查询话题失败"})
+		}
+		return
+	}
+
+	query := db.Table("posts").
+		Joins("INNER JOIN post_tags ON post_tags.post_id = posts.post_id AND post_tags.tag_id = ?", tag.TagID).
+		Scopes(visiblePostsScope(userID))
+	rows, next, err := queryFeedPage(query, cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子列表失败"})
+		return
+	}
+
+	result, err := buildPostPreviews(db, userID, rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子统计失败"})
+		return
+	}
+
+	resp := feedResponse(result, next)
+	resp["tag"] = tag
+	c.JSON(http.StatusOK, resp)
+}
+
+// getTrendingTagsHandler 统计时间窗口内公开动态最多的话题
+func getTrendingTagsHandler(c *gin.Context) {
+	window := config.GlobalConfig.Tag.TrendingWindow
+	if window <= 0 {
+		window = defaultTrendingWindow
+	}
+	if raw := c.Query("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "无效的时间窗口"})
+			return
+		}
+		window = d
+	}
+
+	limit := config.GlobalConfig.Tag.TrendingLimit
+	if limit <= 0 {
+		limit = defaultTrendingLimit
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 && n <= maxTrendingLimit {
+			limit = n
+		}
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+		return
+	}
+
+	// 只统计公开且作者允许所有人查看的动态，避免泄露私密动态的话题
+	var tags []TrendingTag
+	if err := db.Table("post_tags").
+		Select("tags.tag_id, tags.name, COUNT(*) AS post_count").
+		Joins("INNER JOIN tags ON tags.tag_id = post_tags.tag_id").
+		Joins("INNER JOIN posts ON posts.post_id = post_tags.post_id").
+		Joins("LEFT JOIN user_privacy ON user_privacy.uid = posts.user_id").
+		Where("post_tags.created_at >= ?", time.Now().Add(-window)).
+		Where("posts.deleted_at IS NULL AND posts.visibility = ?", model.VisibilityPublic).
+		Where("COALESCE(user_privacy.post_visibility, ?) = ?", model.PostVisibilityEveryone, model.PostVisibilityEveryone).
+		Group("tags.tag_id, tags.name").
+		Order("post_count DESC, tags.tag_id DESC").
+		Limit(limit).
+		Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取热门话题失败"})
+		return
+	}
+	if tags == nil {
+		tags = []TrendingTag{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags":   tags,
+		"window": window.String(),
+	})
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"errors"
+	"gorm.io/gorm"
+)
+
+// 自定义可见范围最多指定的用户数
+const maxPostVisibleUsers = 200
+
+var (
+	errInvalidVisibility = errors.New("无效的可见范围")
+	errVisibleUsers      = errors.New("自定义可见范围只能选择自己的好友")
+)
+
+// normalizeVisibility 校验可见范围，为空时默认公开；custom 时返回去重后的用户列表
+func normalizeVisibility(db *gorm.DB, authorUID, visibility string, visibleUIDs []string) (string, []string, error) {
+	switch visibility {
+	case "":
+		return model.VisibilityPublic, nil, nil
+	case model.VisibilityPublic, model.VisibilityFriends, model.VisibilityPrivate:
+		return visibility, nil, nil
+	case model.VisibilityCustom:
+	default:
+		return "", nil, errInvalidVisibility
+	}
+
+	seen := make(map[string]bool, len(visibleUIDs))
+	uids := make([]string, 0, len(visibleUIDs))
+	for _, uid := range visibleUIDs {
+		if uid != "" && uid != authorUID && !seen[uid] {
+			seen[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+	if len(uids) == 0 || len(uids) > maxPostVisibleUsers {
+		return "", nil, errVisibleUsers
+	}
+
+	var count int64
+	if err := db.Model(&model.Friend{}).Where("user_id = ? AND friend_id IN ?", authorUID, uids).Count(&count).Error; err != nil {
+		return "", nil, err
+	}
+	if int(count) != len(uids) {
+		return "", nil, errVisibleUsers
+	}
+	return visibility, uids, nil
+}
+
+// savePostVisibleUsers 覆盖保存动态的自定义可见用户
+func savePostVisibleUsers(tx *gorm.DB, postID int64, uids []string) error {
+	if err := tx.Where("post_id = ?", postID).Delete(&model.PostVisibleUser{}).Error; err != nil {
+		return err
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+	rows := make([]model.PostVisibleUser, 0, len(uids))
+	for _, uid := range uids {
+		rows = append(rows, model.PostVisibleUser{PostID: postID, UserID: uid})
+	}
+	return tx.Create(&rows).Error
+}
+
+// canViewPost 判断viewer能否查看某条动态：作者的隐私设置和动态自身的可见范围都要满足
+func canViewPost(db *gorm.DB, viewerUID string, post *model.Post) (bool, error) {
+	if viewerUID == post.UserID {
+		return true, nil
+	}
+	if ok, err := canViewPosts(db, viewerUID, post.UserID); err != nil || !ok {
+		return false, err
+	}
+
+	switch post.Visibility {
+	case model.VisibilityPublic, "":
+		return true, nil
+	case model.VisibilityFriends:
+		return isFriend(db, viewerUID, post.UserID), nil
+	case model.VisibilityCustom:
+		var count int64
+		err := db.Model(&model.PostVisibleUser{}).Where("post_id = ? AND user_id = ?", post.PostID, viewerUID).Count(&count).Error
+		return count > 0, err
+	default:
+		return false, nil
+	}
+}
+
+// visiblePostsScope 按作者的隐私设置和动态自身的可见范围过滤posts表，查询需已包含posts表
+func visiblePostsScope(viewerUID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Joins("LEFT JOIN user_privacy post_privacy ON post_privacy.uid = posts.user_id").
+			Where(`posts.user_id = ? OR (
+				(COALESCE(post_privacy.post_visibility, ?) = ?
+					OR (post_privacy.post_visibility = ? AND EXISTS (
+						SELECT 1 FROM friends WHERE friends.user_id = ? AND friends.friend_id = posts.user_id)))
+				AND (posts.visibility = ?
+					OR (posts.visibility = ? AND EXISTS (
+						SELECT 1 FROM friends WHERE friends.user_id = ? AND friends.friend_id = posts.user_id))
+					OR (posts.visibility = ? AND EXISTS (
+						SELECT 1 FROM post_visible_users WHERE post_visible_users.post_id = posts.post_id AND post_visible_users.user_id = ?))))`,
+				viewerUID,
+				model.PostVisibilityEveryone, model.PostVisibilityEveryone,
+				model.PostVisibilityFriends, viewerUID,
+				model.VisibilityPublic,
+				model.VisibilityFriends, viewerUID,
+				model.VisibilityCustom, viewerUID)
+	}
+}
+
+// friendsFeedScope 只保留好友发布的动态
+func friendsFeedScope(viewerUID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.user_id IN (?)",
+			db.Session(&gorm.Session{NewDB: true}).Model(&model.Friend{}).Select("friend_id").Where("user_id = ?", viewerUID))
+	}
+}
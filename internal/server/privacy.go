@@ -0,0 +1,141 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+var errPostsHidden = errors.New("对方设置了动态不可见")
+
+type updatePrivacyRequest struct {
+	SearchPolicy     *string `json:"search_policy"`
+	FriendPolicy     *string `json:"friend_policy"`
+	PostVisibility   *string `json:"post_visibility"`
+	ShowOnlineStatus *bool   `json:"show_online_status"`
+}
+
+// loadPrivacy 读取用户隐私设置，未设置过时返回默认值
+func loadPrivacy(db *gorm.DB, uid string) (model.UserPrivacy, error) {
+	privacy := model.DefaultPrivacy(uid)
+	err := db.Where("uid = ?", uid).First(&privacy).Error
+	if err == gorm.ErrRecordNotFound {
+		return privacy, nil
+	}
+	return privacy, err
+}
+
+// canViewPosts 判断viewer能否查看author的动态
+func canViewPosts(db *gorm.DB, viewerUID, authorUID string) (bool, error) {
+	if viewerUID == authorUID {
+		return true, nil
+	}
+	privacy, err := loadPrivacy(db, authorUID)
+	if err != nil {
+		return false, err
+	}
+	switch privacy.PostVisibility {
+	case model.PostVisibilityEveryone:
+		return true, nil
+	case model.PostVisibilityFriends:
+		return isFriend(db, viewerUID, authorUID), nil
+	default:
+		return false, nil
+	}
+}
+
+// hiddenStatusUIDs 返回关闭了在线状态展示的用户
+func hiddenStatusUIDs(db *gorm.DB, uids []string) map[string]bool {
+	hidden := make(map[string]bool)
+	if len(uids) == 0 {
+		return hidden
+	}
+	var result []string
+	db.Model(&model.UserPrivacy{}).Where("uid IN ? AND show_online_status = ?", uids, false).Pluck("uid", &result)
+	for _, uid := range result {
+		hidden[uid] = true
+	}
+	return hidden
+}
+
+// getPrivacyHandler 获取自己的隐私设置
+func getPrivacyHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	privacy, err := loadPrivacy(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取隐私设置失败"})
+		return
+	}
+	c.JSON(http.StatusOK, privacy)
+}
+
+// updatePrivacyHandler 修改隐私设置，只更新请求中提供的字段
+func updatePrivacyHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req updatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	privacy, err := loadPrivacy(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取隐私设置失败"})
+		return
+	}
+
+	if req.SearchPolicy != nil {
+		switch *req.SearchPolicy {
+		case model.SearchPolicyAll, model.SearchPolicyHandle, model.SearchPolicyNone:
+			privacy.SearchPolicy = *req.SearchPolicy
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的搜索设置"})
+			return
+		}
+	}
+	if req.FriendPolicy != nil {
+		switch *req.FriendPolicy {
+		case model.FriendPolicyApproval, model.FriendPolicyAutoAccept, model.FriendPolicyDisabled:
+			privacy.FriendPolicy = *req.FriendPolicy
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的好友申请设置"})
+			return
+		}
+	}
+	if req.PostVisibility != nil {
+		switch *req.PostVisibility {
+		case model.PostVisibilityEveryone, model.PostVisibilityFriends, model.PostVisibilityNobody:
+			privacy.PostVisibility = *req.PostVisibility
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的动态可见范围"})
+			return
+		}
+	}
+	if req.ShowOnlineStatus != nil {
+		privacy.ShowOnlineStatus = *req.ShowOnlineStatus
+	}
+
+	privacy.UpdatedAt = time.Now()
+	if err := db.Save(&privacy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存隐私设置失败"})
+		return
+	}
+	c.JSON(http.StatusOK, privacy)
+}
@@ -0,0 +1,308 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"NetherLink-server/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLength      = 20
+	maxSignatureLength = 100
+)
+
+type updateProfileRequest struct {
+	Name      *string `json:"name"`
+	Signature *string `json:"signature"`
+}
+
+// UserProfileResponse 用户资料响应结构
+type UserProfileResponse struct {
+	UID       string         `json:"uid"`
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	AvatarURL string         `json:"avatar_url"`
+	Signature string         `json:"signature"`
+	Status    int            `json:"status"`
+	Email     string         `json:"email,omitempty"` // 仅本人可见
+	IsFriend  bool           `json:"is_friend"`
+	CreatedAt string         `json:"created_at"`
+	Stats     *UserPostStats `json:"stats,omitempty"` // 动态数、获赞数、好友数
+}
+
+// ProfileUpdateNotification 资料变更推送结构，好友和群成员收到后更新本地联系人缓存
+type ProfileUpdateNotification struct {
+	UID       string `json:"uid"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+	Signature string `json:"signature"`
+}
+
+// validateName 校验昵称：去除首尾空白后1~20个字符，不能包含控制字符
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("昵称不能为空")
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return "", errors.New("昵称不能超过20个字符")
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", errors.New("昵称包含非法字符")
+	}
+	return name, nil
+}
+
+// validateSignature 校验个性签名：最多100个字符，允许换行
+func validateSignature(signature string) (string, error) {
+	signature = strings.TrimSpace(signature)
+	if utf8.RuneCountInString(signature) > maxSignatureLength {
+		return "", errors.New("个性签名不能超过100个字符")
+	}
+	if strings.IndexFunc(signature, func(r rune) bool { return unicode.IsControl(r) && r != '\n' }) >= 0 {
+		return "", errors.New("个性签名包含非法字符")
+	}
+	return signature, nil
+}
+
+// relatedUserIDs 获取与用户有关联的人：好友以及同群成员
+func relatedUserIDs(db *gorm.DB, uid string) ([]string, error) {
+	var uids []string
+	err := db.Raw(`
+		SELECT friend_id FROM friends WHERE user_id = ?
+		UNION
+		SELECT peer.uid FROM group_members self
+		INNER JOIN group_members peer ON peer.gid = self.gid
+		WHERE self.uid = ? AND peer.uid != ?`, uid, uid, uid).
+		Scan(&uids).Error
+	return uids, err
+}
+
+func isFriend(db *gorm.DB, uid, otherUID string) bool {
+	var count int64
+	db.Model(&model.Friend{}).Where("user_id = ? AND friend_id = ?", uid, otherUID).Count(&count)
+	return count > 0
+}
+
+func buildProfileResponse(user *model.User) UserProfileResponse {
+	return UserProfileResponse{
+		UID:       user.UID,
+		ID:        user.ID,
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+		Signature: user.Signature,
+		Status:    user.Status,
+		CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// getMyProfileHandler 获取自己的资料
+func getMyProfileHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	profile := buildProfileResponse(&user)
+	profile.Email = user.Email
+	if profile.Stats, err = loadUserPostStats(db, userID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取动态统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// getUserProfileHandler 获取指定用户的资料
+func getUserProfileHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+	targetUID := c.Param("uid")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", targetUID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		}
+		return
+	}
+
+	profile := buildProfileResponse(&user)
+	if targetUID == userID {
+		profile.Email = user.Email
+	} else {
+		profile.IsFriend = isFriend(db, userID, targetUID)
+		if hiddenStatusUIDs(db, []string{targetUID})[targetUID] {
+			profile.Status = 0
+		}
+	}
+	if profile.Stats, err = loadUserPostStats(db, userID, targetUID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取动态统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// updateProfileHandler 修改昵称、个性签名
+func (s *HTTPServer) updateProfileHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	var nameHits, signatureHits []ModerationHit
+	if req.Name != nil {
+		name, err := validateName(*req.Name)
+		if err == nil {
+			nameHits, err = moderateFields(&name)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Signature != nil {
+		signature, err := validateSignature(*req.Signature)
+		if err == nil {
+			signatureHits, err = moderateFields(&signature)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["signature"] = signature
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要修改的内容"})
+		return
+	}
+
+	s.applyProfileUpdates(c, userID, updates)
+
+	if len(nameHits) > 0 || len(signatureHits) > 0 {
+		if db, err := getDB(); err == nil {
+			if name, ok := updates["name"].(string); ok {
+				flagForReview(db, model.ModerationSceneUserName, userID, userID, name, nameHits)
+			}
+			if signature, ok := updates["signature"].(string); ok {
+				flagForReview(db, model.ModerationSceneSignature, userID, userID, signature, signatureHits)
+			}
+		}
+	}
+}
+
+// uploadAvatarHandler 上传新头像，与图片上传共用保存逻辑
+func (s *HTTPServer) uploadAvatarHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到文件"})
+		return
+	}
+	defer file.Close()
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	var current model.User
+	if err := db.Select("uid, avatar_url").Where("uid = ?", userID).First(&current).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	filename, err := saveUploadedImage(file, header.Filename)
+	if err != nil {
+		if err == errUnsupportedImage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	avatarURL := utils.GetFullImageURL(filename)
+	if !s.applyProfileUpdates(c, userID, map[string]interface{}{"avatar_url": avatarURL}) {
+		removeUploadedImage(avatarURL)
+		return
+	}
+	// 新头像保存成功后删除旧头像文件
+	if current.AvatarURL != avatarURL {
+		removeUploadedImage(current.AvatarURL)
+	}
+}
+
+// applyProfileUpdates 保存资料修改，返回最新资料并推送给在线的好友和群成员，返回资料是否已保存
+func (s *HTTPServer) applyProfileUpdates(c *gin.Context, userID string, updates map[string]interface{}) bool {
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return false
+	}
+
+	updates["updated_at"] = time.Now()
+	if err := db.Model(&model.User{}).Where("uid = ?", userID).Updates(updates).Error; err != nil {
+		// 可修改的资料中只有用户ID有唯一索引，并发修改为同一ID时由数据库拦截
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": errHandleTaken.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新资料失败"})
+		}
+		return false
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return true
+	}
+
+	if peers, err := relatedUserIDs(db, userID); err == nil {
+		s.events.Publish(peers, "profile_updated", ProfileUpdateNotification{
+			UID:       user.UID,
+			ID:        user.ID,
+			Name:      user.Name,
+			AvatarURL: user.AvatarURL,
+			Signature: user.Signature,
+		})
+	}
+
+	profile := buildProfileResponse(&user)
+	profile.Email = user.Email
+	if profile.Stats, err = loadUserPostStats(db, userID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取动态统计失败"})
+		return true
+	}
+	c.JSON(http.StatusOK, profile)
+	return true
+}
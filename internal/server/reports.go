@@ -0,0 +1,241 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxReportDetailLength = 200
+
+// 举报原因
+var reportReasons = map[string]bool{
+	"spam":       true, // 广告、刷屏
+	"harassment": true, // 辱骂、骚扰
+	"illegal":    true, // 违法违规
+	"sexual":     true, // 色情低俗
+	"other":      true,
+}
+
+var (
+	errReportTargetType = errors.New("无效的举报类型")
+	errReportTarget     = errors.New("举报的内容不存在或无权查看")
+)
+
+type createReportRequest struct {
+	TargetType string `json:"target_type" binding:"required"`
+	TargetID   string `json:"target_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+	Detail     string `json:"detail"`
+}
+
+// snapshotReportTarget 校验举报人能看到举报对象，返回内容作者和内容快照
+func snapshotReportTarget(db *gorm.DB, uid, targetType, targetID string) (string, string, error) {
+	switch targetType {
+	case model.ReportTargetPost:
+		id, err := strconv.ParseInt(targetID, 10, 64)
+		if err != nil {
+			return "", "", errReportTarget
+		}
+		var post model.Post
+		if err := db.First(&post, id).Error; err != nil {
+			return "", "", notFoundAs(err, errReportTarget)
+		}
+		if visible, err := canViewPost(db, uid, &post); err != nil {
+			return "", "", err
+		} else if !visible {
+			return "", "", errReportTarget
+		}
+		return post.UserID, post.Title + "\n" + post.Content, nil
+
+	case model.ReportTargetComment:
+		id, err := strconv.ParseInt(targetID, 10, 64)
+		if err != nil {
+			return "", "", errReportTarget
+		}
+		var comment model.Comment
+		if err := db.First(&comment, id).Error; err != nil {
+			return "", "", notFoundAs(err, errReportTarget)
+		}
+		var post model.Post
+		if err := db.First(&post, comment.PostID).Error; err != nil {
+			return "", "", notFoundAs(err, errReportTarget)
+		}
+		if visible, err := canViewPost(db, uid, &post); err != nil {
+			return "", "", err
+		} else if !visible {
+			return "", "", errReportTarget
+		}
+		return comment.UserID, comment.Content, nil
+
+	case model.ReportTargetPrivateMessage:
+		var message model.PrivateMessage
+		// 只能举报自己参与的私聊消息
+		if err := db.Where("id = ? AND (sender_id = ? OR receiver_id = ?)", targetID, uid, uid).
+			First(&message).Error; err != nil {
+			return "", "", notFoundAs(err, errReportTarget)
+		}
+		return message.SenderID, message.Content, nil
+
+	case model.ReportTargetGroupMessage:
+		var message model.GroupMessage
+		// 只能举报当前所在群的消息
+		if err := db.Where("id = ? AND group_id IN (?)", targetID,
+			db.Model(&model.GroupMember{}).Select("CAST(gid AS CHAR)").Where("uid = ?", uid)).
+			First(&message).Error; err != nil {
+			return "", "", notFoundAs(err, errReportTarget)
+		}
+		return message.SenderID, message.Content, nil
+
+	case model.ReportTargetUser:
+		var user model.User
+		if err := db.Where("uid = ?", targetID).First(&user).Error; err != nil {
+			return "", "", notFoundAs(err, errReportTarget)
+		}
+		return user.UID, user.Name + "\n" + user.Signature, nil
+
+	case model.ReportTargetGroup:
+		var group model.ChatGroup
+		if err := db.Where("gid = ?", targetID).First(&group).Error; err != nil {
+			return "", "", notFoundAs(err, errReportTarget)
+		}
+		return group.OwnerID, group.Name, nil
+
+	default:
+		return "", "", errReportTargetType
+	}
+}
+
+// notFoundAs 记录不存在时返回指定错误，其他错误原样返回
+func notFoundAs(err, notFound error) error {
+	if err == gorm.ErrRecordNotFound {
+		return notFound
+	}
+	return err
+}
+
+// createReportHandler 举报动态、评论、消息、用户或群，同一对象未处理前不能重复举报
+func createReportHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req createReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if !reportReasons[req.Reason] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的举报原因"})
+		return
+	}
+	req.Detail = strings.TrimSpace(req.Detail)
+	if utf8.RuneCountInString(req.Detail) > maxReportDetailLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "举报说明不能超过200个字符"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	targetUID, content, err := snapshotReportTarget(db, userID, req.TargetType, req.TargetID)
+	if err != nil {
+		if err == errReportTargetType || err == errReportTarget {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询举报内容失败"})
+		}
+		return
+	}
+	if targetUID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能举报自己"})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&model.Report{}).
+		Where("reporter_uid = ? AND target_type = ? AND target_id = ? AND status = ?",
+			userID, req.TargetType, req.TargetID, model.ReportStatusPending).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询举报记录失败"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "你已举报过该内容，请等待处理"})
+		return
+	}
+
+	report := model.Report{
+		ReporterUID: userID,
+		TargetType:  req.TargetType,
+		TargetID:    req.TargetID,
+		TargetUID:   targetUID,
+		Reason:      req.Reason,
+		Detail:      req.Detail,
+		Content:     content,
+		Status:      model.ReportStatusPending,
+		CreatedAt:   time.Now(),
+	}
+	if err := db.Create(&report).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交举报失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report_id": report.ReportID, "status": report.Status})
+}
+
+// getMyReportsHandler 分页查看自己提交的举报及处理结果
+func getMyReportsHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+	page, pageSize := parsePage(c)
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	query := db.Model(&model.Report{}).Where("reporter_uid = ?", userID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取举报记录失败"})
+		return
+	}
+
+	var reports []model.Report
+	if err := query.Session(&gorm.Session{}).
+		Select("report_id, target_type, target_id, reason, detail, status, action, handled_at, created_at").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取举报记录失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(reports))
+	for _, report := range reports {
+		items = append(items, gin.H{
+			"report_id":   report.ReportID,
+			"target_type": report.TargetType,
+			"target_id":   report.TargetID,
+			"reason":      report.Reason,
+			"detail":      report.Detail,
+			"status":      report.Status,
+			"action":      report.Action,
+			"handled_at":  formatOptionalTime(report.HandledAt),
+			"created_at":  report.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"reports": items,
+		"total":   total,
+		"page":    page,
+	})
+}
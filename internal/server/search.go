@@ -0,0 +1,355 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// 与 MySQL 默认的 ngram_token_size 一致，更短的关键词无法命中全文索引，退回 LIKE
+	ngramTokenSize   = 2
+	maxKeywordLength = 50
+
+	// 摘要长度和命中位置前保留的字数
+	snippetLength  = 80
+	snippetContext = 20
+
+	messageScopeAll     = "all"
+	messageScopePrivate = "private"
+	messageScopeGroup   = "group"
+)
+
+// SearchQuery 搜索关键词和分页参数
+type SearchQuery struct {
+	Keyword  string
+	Page     int
+	PageSize int
+}
+
+func (q SearchQuery) offset() int {
+	return (q.Page - 1) * q.PageSize
+}
+
+// PostSearchResult 动态搜索结果，Highlight 字段中命中的关键词用 <em> 标记，其余内容已转义
+type PostSearchResult struct {
+	PostID           int64     `gorm:"column:post_id" json:"post_id"`
+	UserID           string    `gorm:"column:user_id" json:"user_id"`
+	UserName         string    `gorm:"column:name" json:"user_name"`
+	UserAvatar       string    `gorm:"column:avatar_url" json:"user_avatar"`
+	Title            string    `gorm:"column:title" json:"-"`
+	Content          string    `gorm:"column:content" json:"-"`
+	TitleHighlight   string    `gorm:"-" json:"title_highlight"`
+	ContentHighlight string    `gorm:"-" json:"content_highlight"`
+	CreatedAt        time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// CommentSearchResult 评论搜索结果
+type CommentSearchResult struct {
+	CommentID  int64     `gorm:"column:comment_id" json:"comment_id"`
+	PostID     int64     `gorm:"column:post_id" json:"post_id"`
+	UserID     string    `gorm:"column:user_id" json:"user_id"`
+	UserName   string    `gorm:"column:name" json:"user_name"`
+	UserAvatar string    `gorm:"column:avatar_url" json:"user_avatar"`
+	Content    string    `gorm:"column:content" json:"-"`
+	Highlight  string    `gorm:"-" json:"highlight"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// MessageSearchResult 聊天记录搜索结果，私聊的 Conversation 为对方的UID，群聊为群ID
+type MessageSearchResult struct {
+	MessageID    int64     `gorm:"column:id" json:"message_id"`
+	IsGroup      bool      `gorm:"column:is_group" json:"is_group"`
+	Conversation string    `gorm:"column:conversation" json:"conversation"`
+	SenderID     string    `gorm:"column:sender_id" json:"sender_id"`
+	SenderName   string    `gorm:"column:name" json:"sender_name"`
+	Content      string    `gorm:"column:content" json:"-"`
+	Highlight    string    `gorm:"-" json:"highlight"`
+	Timestamp    time.Time `gorm:"column:timestamp" json:"timestamp"`
+}
+
+// Searcher 全文检索接口，结果都已按查看者的可见范围和群成员身份过滤
+type Searcher interface {
+	SearchPosts(db *gorm.DB, viewerUID string, q SearchQuery) ([]PostSearchResult, int64, error)
+	SearchComments(db *gorm.DB, viewerUID string, q SearchQuery) ([]CommentSearchResult, int64, error)
+	SearchMessages(db *gorm.DB, uid, scope string, q SearchQuery) ([]MessageSearchResult, int64, error)
+}
+
+// mysqlSearcher 基于 MySQL FULLTEXT 索引（ngram 分词，支持中文）的实现
+type mysqlSearcher struct{}
+
+var searcher Searcher = mysqlSearcher{}
+
+// matchCondition 生成全文匹配条件，关键词短于分词长度时退回 LIKE
+func matchCondition(columns []string, keyword string) (string, []interface{}) {
+	if utf8.RuneCountInString(keyword) >= ngramTokenSize {
+		// 作为短语匹配，去掉引号避免破坏布尔模式语法
+		phrase := `"` + strings.ReplaceAll(keyword, `"`, " ") + `"`
+		return "MATCH(" + strings.Join(columns, ", ") + ") AGAINST (? IN BOOLEAN MODE)", []interface{}{phrase}
+	}
+
+	pattern := "%" + escapeLike(keyword) + "%"
+	conds := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		conds = append(conds, column+" LIKE ?")
+		args = append(args, pattern)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (mysqlSearcher) SearchPosts(db *gorm.DB, viewerUID string, q SearchQuery) ([]PostSearchResult, int64, error) {
+	cond, args := matchCondition([]string{"posts.title", "posts.content"}, q.Keyword)
+	query := db.Table("posts").
+		Scopes(visiblePostsScope(viewerUID)).
+		Where("posts.deleted_at IS NULL").
+		Where(cond, args...).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	results := []PostSearchResult{}
+	if err := query.
+		Select("posts.post_id, posts.user_id, users.name, users.avatar_url, posts.title, posts.content, posts.created_at").
+		Joins("LEFT JOIN users ON users.uid = posts.user_id").
+		Order("posts.created_at DESC, posts.post_id DESC").
+		Offset(q.offset()).
+		Limit(q.PageSize).
+		Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range results {
+		results[i].TitleHighlight = highlightSnippet(results[i].Title, q.Keyword)
+		results[i].ContentHighlight = highlightSnippet(results[i].Content, q.Keyword)
+	}
+	return results, total, nil
+}
+
+func (mysqlSearcher) SearchComments(db *gorm.DB, viewerUID string, q SearchQuery) ([]CommentSearchResult, int64, error) {
+	cond, args := matchCondition([]string{"comments.content"}, q.Keyword)
+	query := db.Table("comments").
+		Joins("INNER JOIN posts ON posts.post_id = comments.post_id").
+		Scopes(visiblePostsScope(viewerUID)).
+		Where("comments.deleted_at IS NULL AND posts.deleted_at IS NULL").
+		Where(cond, args...).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	results := []CommentSearchResult{}
+	if err := query.
+		Select("comments.comment_id, comments.post_id, comments.user_id, users.name, users.avatar_url, comments.content, comments.created_at").
+		Joins("LEFT JOIN users ON users.uid = comments.user_id").
+		Order("comments.created_at DESC, comments.comment_id DESC").
+		Offset(q.offset()).
+		Limit(q.PageSize).
+		Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range results {
+		results[i].Highlight = highlightSnippet(results[i].Content, q.Keyword)
+	}
+	return results, total, nil
+}
+
+func (mysqlSearcher) SearchMessages(db *gorm.DB, uid, scope string, q SearchQuery) ([]MessageSearchResult, int64, error) {
+	var total int64
+	var parts []interface{}
+
+	if scope == messageScopeAll || scope == messageScopePrivate {
+		cond, args := matchCondition([]string{"pm.content"}, q.Keyword)
+		query := db.Table("private_messages pm").
+			Where("pm.sender_id = ? OR pm.receiver_id = ?", uid, uid).
+			Where(cond, args...).
+			Session(&gorm.Session{})
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+		total += count
+		parts = append(parts, query.Select(`pm.id, FALSE AS is_group,
+			CASE WHEN pm.sender_id = ? THEN pm.receiver_id ELSE pm.sender_id END AS conversation,
+			pm.sender_id, pm.content, pm.timestamp`, uid))
+	}
+	if scope == messageScopeAll || scope == messageScopeGroup {
+		// 只能搜索当前所在群的消息
+		cond, args := matchCondition([]string{"gm.content"}, q.Keyword)
+		query := db.Table("group_message gm").
+			Where("gm.group_id IN (?)", db.Model(&model.GroupMember{}).Select("CAST(gid AS CHAR)").Where("uid = ?", uid)).
+			Where(cond, args...).
+			Session(&gorm.Session{})
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+		total += count
+		parts = append(parts, query.Select("gm.id, TRUE AS is_group, gm.group_id AS conversation, gm.sender_id, gm.content, gm.timestamp"))
+	}
+
+	union := db.Raw(strings.TrimSuffix(strings.Repeat("(?) UNION ALL ", len(parts)), " UNION ALL "), parts...)
+
+	results := []MessageSearchResult{}
+	if err := db.Table("(?) AS m", union).
+		Select("m.id, m.is_group, m.conversation, m.sender_id, users.name, m.content, m.timestamp").
+		Joins("LEFT JOIN users ON users.uid = m.sender_id").
+		Order("m.timestamp DESC, m.id DESC").
+		Offset(q.offset()).
+		Limit(q.PageSize).
+		Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range results {
+		results[i].Highlight = highlightSnippet(results[i].Content, q.Keyword)
+	}
+	return results, total, nil
+}
+
+// highlightSnippet 截取关键词附近的一段文字，转义后用 <em> 标记命中的关键词
+func highlightSnippet(text, keyword string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	kw := []rune(strings.ToLower(keyword))
+
+	indexFrom := func(from int) int {
+		for i := from; i+len(kw) <= len(lower); i++ {
+			if string(lower[i:i+len(kw)]) == string(kw) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	start := 0
+	if first := indexFrom(0); first > snippetContext {
+		start = first - snippetContext
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	pos := start
+	for len(kw) > 0 {
+		i := indexFrom(pos)
+		if i < 0 || i+len(kw) > end {
+			break
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:i])))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(string(runes[i : i+len(kw)])))
+		b.WriteString("</em>")
+		pos = i + len(kw)
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// parseSearchQuery 读取关键词和分页参数，失败时已写入响应
+func parseSearchQuery(c *gin.Context) (SearchQuery, bool) {
+	keyword := strings.TrimSpace(c.Query("keyword"))
+	if keyword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词不能为空"})
+		return SearchQuery{}, false
+	}
+	if utf8.RuneCountInString(keyword) > maxKeywordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词过长"})
+		return SearchQuery{}, false
+	}
+	page, pageSize := parsePage(c)
+	return SearchQuery{Keyword: keyword, Page: page, PageSize: pageSize}, true
+}
+
+// searchPostsHandler 搜索动态标题和内容
+func searchPostsHandler(c *gin.Context) {
+	q, ok := parseSearchQuery(c)
+	if !ok {
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	results, total, err := searcher.SearchPosts(db, c.GetString("user_id"), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索动态失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"posts": results, "total": total, "page": q.Page})
+}
+
+// searchCommentsHandler 搜索可见动态下的评论
+func searchCommentsHandler(c *gin.Context) {
+	q, ok := parseSearchQuery(c)
+	if !ok {
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	results, total, err := searcher.SearchComments(db, c.GetString("user_id"), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索评论失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"comments": results, "total": total, "page": q.Page})
+}
+
+// searchMessagesHandler 搜索自己的私聊记录和所在群的群聊记录，type 可选 all、private、group
+func searchMessagesHandler(c *gin.Context) {
+	q, ok := parseSearchQuery(c)
+	if !ok {
+		return
+	}
+	scope := c.DefaultQuery("type", messageScopeAll)
+	if scope != messageScopeAll && scope != messageScopePrivate && scope != messageScopeGroup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的消息类型"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	results, total, err := searcher.SearchMessages(db, c.GetString("user_id"), scope, q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索聊天记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": results, "total": total, "page": q.Page})
+}
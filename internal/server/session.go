@@ -0,0 +1,261 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+var (
+	errInvalidToken   = errors.New("无效的token")
+	errSessionRevoked = errors.New("登录状态已失效，请重新登录")
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// tokenPair 登录或刷新后返回给客户端的令牌
+type tokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // access token有效期（秒）
+}
+
+// createSession 为用户创建新的登录会话并签发令牌
+func createSession(db *gorm.DB, uid string, c *gin.Context) (*tokenPair, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := model.Session{
+		SessionID:   uuid.New().String(),
+		UID:         uid,
+		RefreshHash: hashToken(refreshToken),
+		UserAgent:   c.Request.UserAgent(),
+		IP:          c.ClientIP(),
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(config.GlobalConfig.JWT.RefreshExpire),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	token, err := generateJWT(uid, session.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(config.GlobalConfig.JWT.Expire.Seconds()),
+	}, nil
+}
+
+// generateRefreshToken 生成随机的refresh token
+func generateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken 数据库中只保存token的哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAccessToken 解析access token，并校验其所属会话未被吊销
+func parseAccessToken(tokenString string) (uid string, sessionID string, err error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.GlobalConfig.JWT.Secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil || !token.Valid {
+		return "", "", errInvalidToken
+	}
+
+	if typ, _ := claims["typ"].(string); typ != "access" {
+		return "", "", errInvalidToken
+	}
+	uid, ok := claims["uid"].(string)
+	if !ok {
+		return "", "", errInvalidToken
+	}
+	sessionID, ok = claims["sid"].(string)
+	if !ok {
+		return "", "", errInvalidToken
+	}
+
+	db, err := getDB()
+	if err != nil {
+		return "", "", err
+	}
+	if err := checkSession(db, uid, sessionID); err != nil {
+		return "", "", err
+	}
+
+	return uid, sessionID, nil
+}
+
+// checkSession 检查会话是否存在、未过期且未被吊销
+func checkSession(db *gorm.DB, uid, sessionID string) error {
+	var count int64
+	if err := db.Model(&model.Session{}).
+		Where("session_id = ? AND uid = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, uid, time.Now()).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errSessionRevoked
+	}
+	return nil
+}
+
+// revokeSession 吊销单个会话
+func revokeSession(db *gorm.DB, sessionID string) error {
+	return db.Model(&model.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeUserSessions 吊销用户的全部会话
+func revokeUserSessions(db *gorm.DB, uid string) error {
+	return db.Model(&model.Session{}).
+		Where("uid = ? AND revoked_at IS NULL", uid).
+		Update("revoked_at", time.Now()).Error
+}
+
+// refreshTokenHandler 使用refresh token换取新的令牌，旧refresh token随即失效
+func (s *HTTPServer) refreshTokenHandler(c *gin.Context) {
+	var req refreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	hash := hashToken(req.RefreshToken)
+	var session model.Session
+	if err := db.Where("refresh_hash = ?", hash).First(&session).Error; err != nil {
+		// 已轮换掉的旧token被再次使用，说明token可能已泄露，直接吊销整个会话
+		if db.Where("prev_refresh_hash = ?", hash).First(&session).Error == nil {
+			revokeSession(db, session.SessionID)
+			s.ws.DisconnectSession(session.UID, session.SessionID, "登录状态异常，请重新登录")
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token无效"})
+		return
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token已过期"})
+		return
+	}
+	if suspension, err := activeSuspension(db, session.UID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询账号状态失败"})
+		return
+	} else if suspension != nil && !suspension.ReadOnly {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     suspensionMessage(suspension),
+			"suspended": newSuspensionInfo(suspension),
+		})
+		return
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	// 以旧哈希为条件更新，防止同一个refresh token被并发使用两次
+	result := db.Model(&model.Session{}).
+		Where("session_id = ? AND refresh_hash = ?", session.SessionID, hash).
+		Updates(map[string]interface{}{
+			"refresh_hash":      hashToken(refreshToken),
+			"prev_refresh_hash": hash,
+			"last_used_at":      now,
+			"expires_at":        now.Add(config.GlobalConfig.JWT.RefreshExpire),
+			"ip":                c.ClientIP(),
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新token失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token无效"})
+		return
+	}
+
+	token, err := generateJWT(session.UID, session.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(config.GlobalConfig.JWT.Expire.Seconds()),
+	})
+}
+
+// logoutHandler 退出当前设备
+func (s *HTTPServer) logoutHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+	sessionID := c.GetString("session_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	if err := revokeSession(db, sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+	s.ws.DisconnectSession(userID, sessionID, "已退出登录")
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+}
+
+// logoutAllHandler 退出所有设备
+func (s *HTTPServer) logoutAllHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	if err := revokeUserSessions(db, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+	s.ws.DisconnectUser(userID, "账号已在所有设备退出登录")
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出所有设备"})
+}
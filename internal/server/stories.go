@@ -0,0 +1,543 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"NetherLink-server/pkg/utils"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	storyUploadDir      = "uploads/stories"
+	maxStoryContentLen  = 200
+	defaultStoryTTL     = 24 * time.Hour
+	storySweepBatchSize = 500
+)
+
+// StoryRingItem 快拍列表顶部的一个头像圈
+type StoryRingItem struct {
+	UID        string `json:"uid"`
+	Name       string `json:"name"`
+	AvatarURL  string `json:"avatar_url"`
+	StoryCount int    `json:"story_count"`
+	HasUnseen  bool   `json:"has_unseen"`
+	LatestAt   string `json:"latest_at"`
+}
+
+// StoryItem 返回给客户端的快拍，作者本人额外看到浏览人数
+type StoryItem struct {
+	StoryID   int64  `json:"story_id"`
+	UserID    string `json:"user_id"`
+	Type      string `json:"type"`
+	Content   string `json:"content"`
+	ImageURL  string `json:"image_url"`
+	Viewed    bool   `json:"viewed"`
+	ViewCount *int64 `json:"view_count,omitempty"`
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+// StoryViewer 快拍的浏览者
+type StoryViewer struct {
+	UID       string `json:"uid"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+	ViewedAt  string `json:"viewed_at"`
+}
+
+func storyImageURL(filename string) string {
+	return fmt.Sprintf("%s/uploads/stories/%s", config.GlobalConfig.Server.HTTP.BaseURL, filename)
+}
+
+func storyTTL() time.Duration {
+	if ttl := config.GlobalConfig.Story.TTL; ttl > 0 {
+		return ttl
+	}
+	return defaultStoryTTL
+}
+
+// removeStoryFiles 删除快拍图片文件
+func removeStoryFiles(imageURLs []string) {
+	for _, url := range imageURLs {
+		if url == "" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(storyUploadDir, path.Base(url))); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除快拍图片失败: %v", err)
+		}
+	}
+}
+
+// canViewStories 快拍只对作者本人和好友可见，同时遵守作者的动态隐私设置
+func canViewStories(db *gorm.DB, viewerUID, authorUID string) (bool, error) {
+	if viewerUID == authorUID {
+		return true, nil
+	}
+	if !isFriend(db, viewerUID, authorUID) {
+		return false, nil
+	}
+	return canViewPosts(db, viewerUID, authorUID)
+}
+
+// createStoryHandler 发布快拍，图片和文字至少有一个，带图片时文字作为配文
+func createStoryHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	content := strings.TrimSpace(c.PostForm("content"))
+	if utf8.RuneCountInString(content) > maxStoryContentLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("快拍文字不能超过%d字", maxStoryContentLen)})
+		return
+	}
+	file, err := c.FormFile("image")
+	if err != nil && err != http.ErrMissingFile {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求格式"})
+		return
+	}
+	if file == nil && content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "快拍需要图片或文字"})
+		return
+	}
+	hits, err := moderateFields(&content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	now := time.Now()
+	story := &model.Story{
+		UserID:    userID,
+		Type:      model.StoryTypeText,
+		Content:   content,
+		ExpiresAt: now.Add(storyTTL()),
+		CreatedAt: now,
+	}
+	if file != nil {
+		imageURL, err := saveStoryImage(c, userID, file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		story.Type = model.StoryTypeImage
+		story.ImageURL = imageURL
+	}
+
+	if err := db.Create(story).Error; err != nil {
+		removeStoryFiles([]string{story.ImageURL})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发布快拍失败"})
+		return
+	}
+	flagForReview(db, model.ModerationSceneStory, strconv.FormatInt(story.StoryID, 10), userID, story.Content, hits)
+
+	var zero int64
+	c.JSON(http.StatusOK, newStoryItem(story, false, &zero))
+}
+
+// saveStoryImage 保存快拍图片并检查尺寸
+func saveStoryImage(c *gin.Context, userID string, file *multipart.FileHeader) (string, error) {
+	if err := validatePostImages([]*multipart.FileHeader{file}); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(storyUploadDir, 0755); err != nil {
+		return "", errors.New("创建目录失败")
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	filename := fmt.Sprintf("story_%s_%d%s", userID, time.Now().UnixNano(), ext)
+	savePath := filepath.Join(storyUploadDir, filename)
+	if err := c.SaveUploadedFile(file, savePath); err != nil {
+		return "", errors.New("保存图片失败")
+	}
+
+	width, height, err := utils.DecodeImageSize(savePath)
+	if err != nil {
+		os.Remove(savePath)
+		return "", errPostImageType
+	}
+	if width*height > maxPostImagePixels {
+		os.Remove(savePath)
+		return "", errPostImageTooLarge
+	}
+	return storyImageURL(filename), nil
+}
+
+func newStoryItem(story *model.Story, viewed bool, viewCount *int64) StoryItem {
+	return StoryItem{
+		StoryID:   story.StoryID,
+		UserID:    story.UserID,
+		Type:      story.Type,
+		Content:   story.Content,
+		ImageURL:  story.ImageURL,
+		Viewed:    viewed,
+		ViewCount: viewCount,
+		ExpiresAt: story.ExpiresAt.Format("2006-01-02 15:04:05"),
+		CreatedAt: story.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// getStoryRingHandler 获取快拍头像圈：自己排在最前，其余有未看快拍的好友优先，再按最新发布时间排序
+func getStoryRingHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	type ringRow struct {
+		UserID      string    `gorm:"column:user_id"`
+		StoryCount  int       `gorm:"column:story_count"`
+		UnseenCount int       `gorm:"column:unseen_count"`
+		LatestAt    time.Time `gorm:"column:latest_at"`
+	}
+	var rows []ringRow
+	if err := db.Table("stories").
+		Select("stories.user_id, COUNT(*) AS story_count, "+
+			"SUM(CASE WHEN story_views.story_id IS NULL THEN 1 ELSE 0 END) AS unseen_count, "+
+			"MAX(stories.created_at) AS latest_at").
+		Joins("LEFT JOIN story_views ON story_views.story_id = stories.story_id AND story_views.viewer_uid = ?", userID).
+		Where("stories.expires_at > ?", time.Now()).
+		Where("stories.user_id = ? OR stories.user_id IN (?)", userID,
+			db.Model(&model.Friend{}).Select("friend_id").Where("user_id = ?", userID)).
+		Group("stories.user_id").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取快拍失败"})
+		return
+	}
+
+	var visible []ringRow
+	uids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ok, err := canViewStories(db, userID, row.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取快拍失败"})
+			return
+		}
+		if ok {
+			visible = append(visible, row)
+			uids = append(uids, row.UserID)
+		}
+	}
+
+	var users []model.User
+	if len(uids) > 0 {
+		if err := db.Select("uid, name, avatar_url").Where("uid IN ?", uids).Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+			return
+		}
+	}
+	userMap := make(map[string]model.User, len(users))
+	for _, user := range users {
+		userMap[user.UID] = user
+	}
+
+	sort.SliceStable(visible, func(i, j int) bool {
+		a, b := visible[i], visible[j]
+		if (a.UserID == userID) != (b.UserID == userID) {
+			return a.UserID == userID
+		}
+		if (a.UnseenCount > 0) != (b.UnseenCount > 0) {
+			return a.UnseenCount > 0
+		}
+		return a.LatestAt.After(b.LatestAt)
+	})
+
+	ring := make([]StoryRingItem, 0, len(visible))
+	for _, row := range visible {
+		user := userMap[row.UserID]
+		ring = append(ring, StoryRingItem{
+			UID:        row.UserID,
+			Name:       user.Name,
+			AvatarURL:  user.AvatarURL,
+			StoryCount: row.StoryCount,
+			HasUnseen:  row.UserID != userID && row.UnseenCount > 0,
+			LatestAt:   row.LatestAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"ring": ring})
+}
+
+// getUserStoriesHandler 按发布时间顺序获取某个用户未过期的快拍
+func getUserStoriesHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+	targetUID := c.Param("uid")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	if ok, err := canViewStories(db, userID, targetUID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取快拍失败"})
+		return
+	} else if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该用户的快拍"})
+		return
+	}
+
+	var stories []model.Story
+	if err := db.Where("user_id = ? AND expires_at > ?", targetUID, time.Now()).
+		Order("created_at ASC").Find(&stories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取快拍失败"})
+		return
+	}
+
+	storyIDs := make([]int64, 0, len(stories))
+	for _, story := range stories {
+		storyIDs = append(storyIDs, story.StoryID)
+	}
+
+	viewed := make(map[int64]bool)
+	viewCounts := make(map[int64]int64)
+	if len(storyIDs) > 0 {
+		if targetUID == userID {
+			type countRow struct {
+				StoryID int64 `gorm:"column:story_id"`
+				Total   int64 `gorm:"column:total"`
+			}
+			var counts []countRow
+			if err := db.Model(&model.StoryView{}).Select("story_id, COUNT(*) AS total").
+				Where("story_id IN ?", storyIDs).Group("story_id").Scan(&counts).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取浏览记录失败"})
+				return
+			}
+			for _, count := range counts {
+				viewCounts[count.StoryID] = count.Total
+			}
+		} else {
+			var viewedIDs []int64
+			if err := db.Model(&model.StoryView{}).Where("story_id IN ? AND viewer_uid = ?", storyIDs, userID).
+				Pluck("story_id", &viewedIDs).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取浏览记录失败"})
+				return
+			}
+			for _, id := range viewedIDs {
+				viewed[id] = true
+			}
+		}
+	}
+
+	result := make([]StoryItem, 0, len(stories))
+	for i := range stories {
+		var viewCount *int64
+		if targetUID == userID {
+			count := viewCounts[stories[i].StoryID]
+			viewCount = &count
+		}
+		result = append(result, newStoryItem(&stories[i], viewed[stories[i].StoryID], viewCount))
+	}
+	c.JSON(http.StatusOK, gin.H{"stories": result})
+}
+
+// loadActiveStory 查询未过期的快拍，失败时已写入响应
+func loadActiveStory(c *gin.Context, db *gorm.DB) (*model.Story, bool) {
+	storyID, err := strconv.ParseInt(c.Param("story_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的快拍ID"})
+		return nil, false
+	}
+
+	var story model.Story
+	if err := db.Where("story_id = ? AND expires_at > ?", storyID, time.Now()).First(&story).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "快拍不存在或已过期"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询快拍失败"})
+		}
+		return nil, false
+	}
+	return &story, true
+}
+
+// viewStoryHandler 记录浏览，重复浏览只保留第一次，作者看自己的快拍不记录
+func viewStoryHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	story, ok := loadActiveStory(c, db)
+	if !ok {
+		return
+	}
+	if story.UserID == userID {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+		return
+	}
+	if visible, err := canViewStories(db, userID, story.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询快拍失败"})
+		return
+	} else if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该快拍"})
+		return
+	}
+
+	view := model.StoryView{StoryID: story.StoryID, ViewerUID: userID, ViewedAt: time.Now()}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&view).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "记录浏览失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
+
+// getStoryViewersHandler 作者查看快拍的浏览者，最近浏览的在前
+func getStoryViewersHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	story, ok := loadActiveStory(c, db)
+	if !ok {
+		return
+	}
+	if story.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能查看自己快拍的浏览者"})
+		return
+	}
+
+	type viewerRow struct {
+		UID       string    `gorm:"column:uid"`
+		Name      string    `gorm:"column:name"`
+		AvatarURL string    `gorm:"column:avatar_url"`
+		ViewedAt  time.Time `gorm:"column:viewed_at"`
+	}
+	var rows []viewerRow
+	if err := db.Table("story_views").
+		Select("users.uid, users.name, users.avatar_url, story_views.viewed_at").
+		Joins("JOIN users ON users.uid = story_views.viewer_uid").
+		Where("story_views.story_id = ?", story.StoryID).
+		Order("story_views.viewed_at DESC").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取浏览记录失败"})
+		return
+	}
+
+	viewers := make([]StoryViewer, 0, len(rows))
+	for _, row := range rows {
+		viewers = append(viewers, StoryViewer{
+			UID:       row.UID,
+			Name:      row.Name,
+			AvatarURL: row.AvatarURL,
+			ViewedAt:  row.ViewedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"story_id": story.StoryID, "total": len(viewers), "viewers": viewers})
+}
+
+// deleteStoryHandler 作者提前删除快拍
+func deleteStoryHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	story, ok := loadActiveStory(c, db)
+	if !ok {
+		return
+	}
+	if story.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能删除自己的快拍"})
+		return
+	}
+
+	if err := deleteStories(db, []int64{story.StoryID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除快拍失败"})
+		return
+	}
+	removeStoryFiles([]string{story.ImageURL})
+	c.JSON(http.StatusOK, gin.H{"message": "快拍已删除"})
+}
+
+// deleteStories 删除快拍及其浏览记录，图片文件由调用方在事务成功后删除
+func deleteStories(db *gorm.DB, storyIDs []int64) error {
+	if len(storyIDs) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("story_id IN ?", storyIDs).Delete(&model.StoryView{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("story_id IN ?", storyIDs).Delete(&model.Story{}).Error
+	})
+}
+
+// startStorySweeper 定期删除过期的快拍和图片文件
+func startStorySweeper(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sweepExpiredStories()
+		}
+	}()
+}
+
+func sweepExpiredStories() {
+	db, err := getDB()
+	if err != nil {
+		log.Printf("清理过期快拍失败: %v", err)
+		return
+	}
+
+	// 分批处理，避免一次删除过多记录
+	for {
+		var stories []model.Story
+		if err := db.Where("expires_at <= ?", time.Now()).Limit(storySweepBatchSize).Find(&stories).Error; err != nil {
+			log.Printf("查询过期快拍失败: %v", err)
+			return
+		}
+		if len(stories) == 0 {
+			return
+		}
+
+		ids := make([]int64, 0, len(stories))
+		imageURLs := make([]string, 0, len(stories))
+		for _, story := range stories {
+			ids = append(ids, story.StoryID)
+			imageURLs = append(imageURLs, story.ImageURL)
+		}
+		if err := deleteStories(db, ids); err != nil {
+			log.Printf("删除过期快拍失败: %v", err)
+			return
+		}
+		removeStoryFiles(imageURLs)
+		if len(stories) < storySweepBatchSize {
+			return
+		}
+	}
+}
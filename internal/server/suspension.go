@@ -0,0 +1,311 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const loginReasonSuspended = "suspended"
+
+type suspendUserRequest struct {
+	Reason   string `json:"reason" binding:"required"`
+	Duration string `json:"duration"`  // 封禁时长，如 72h，为空表示永久封禁
+	ReadOnly bool   `json:"read_only"` // 只读封禁：可以登录浏览，但不能发布内容和聊天
+}
+
+type liftSuspensionRequest struct {
+	Note string `json:"note"`
+}
+
+// SuspensionInfo 返回给被封禁用户的封禁信息
+type SuspensionInfo struct {
+	Reason    string      `json:"reason"`
+	ReadOnly  bool        `json:"read_only"`
+	ExpiresAt interface{} `json:"expires_at"` // 为空表示永久封禁
+}
+
+func newSuspensionInfo(suspension *model.UserSuspension) SuspensionInfo {
+	return SuspensionInfo{
+		Reason:    suspension.Reason,
+		ReadOnly:  suspension.ReadOnly,
+		ExpiresAt: formatOptionalTime(suspension.ExpiresAt),
+	}
+}
+
+// suspensionMessage 生成提示文字，包含解封时间
+func suspensionMessage(suspension *model.UserSuspension) string {
+	state := "账号已被封禁"
+	if suspension.ReadOnly {
+		state = "账号处于只读状态"
+	}
+	if suspension.ExpiresAt == nil {
+		return fmt.Sprintf("%s，原因：%s", state, suspension.Reason)
+	}
+	return fmt.Sprintf("%s至%s，原因：%s", state, suspension.ExpiresAt.Format("2006-01-02 15:04:05"), suspension.Reason)
+}
+
+// activeSuspension 查询用户当前生效的封禁，完全封禁优先于只读封禁，没有时返回nil
+func activeSuspension(db *gorm.DB, uid string) (*model.UserSuspension, error) {
+	var suspension model.UserSuspension
+	err := db.Where("uid = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", uid, time.Now()).
+		Order("read_only ASC, expires_at IS NULL DESC, expires_at DESC").
+		First(&suspension).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &suspension, nil
+}
+
+// rejectSuspendedLogin 完全封禁的账号拒绝登录并写入响应，只读封禁允许登录
+func rejectSuspendedLogin(c *gin.Context, db *gorm.DB, user *model.User) bool {
+	suspension, err := activeSuspension(db, user.UID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询账号状态失败"})
+		return true
+	}
+	if suspension == nil || suspension.ReadOnly {
+		return false
+	}
+	recordLogin(c, user, user.Email, false, loginReasonSuspended)
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":     suspensionMessage(suspension),
+		"suspended": newSuspensionInfo(suspension),
+	})
+	return true
+}
+
+// readOnlyGuard 拦截只读封禁用户的发布类请求，需放在 authMiddleware 之后
+func readOnlyGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value, ok := c.Get("suspension"); ok {
+			suspension := value.(*model.UserSuspension)
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":      -1,
+				"message":   suspensionMessage(suspension),
+				"suspended": newSuspensionInfo(suspension),
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// createSuspension 新增封禁记录，处理举报和直接封禁共用
+func createSuspension(tx *gorm.DB, uid, adminUID, reason string, readOnly bool, expiresAt *time.Time, reportID *int64) (*model.UserSuspension, error) {
+	suspension := &model.UserSuspension{
+		UID:       uid,
+		AdminUID:  adminUID,
+		ReportID:  reportID,
+		Reason:    reason,
+		ReadOnly:  readOnly,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := tx.Create(suspension).Error; err != nil {
+		return nil, err
+	}
+	return suspension, nil
+}
+
+// parseSuspendDuration 解析封禁时长，为空表示永久封禁
+func parseSuspendDuration(raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	duration, err := time.ParseDuration(raw)
+	if err != nil || duration <= 0 {
+		return nil, errors.New("无效的封禁时长")
+	}
+	expiresAt := time.Now().Add(duration)
+	return &expiresAt, nil
+}
+
+// NotifySuspended 推送封禁信息，完全封禁时立即断开在线连接
+func (s *WSServer) NotifySuspended(uid string, suspension *model.UserSuspension) {
+	conn, ok := s.connections.Load(uid)
+	if !ok {
+		return
+	}
+	wsConn, ok := conn.(*WSConnection)
+	if !ok {
+		return
+	}
+	s.sendSuspended(wsConn, suspension)
+	if !suspension.ReadOnly {
+		wsConn.conn.Close()
+	}
+}
+
+func (s *WSServer) sendSuspended(wsConn *WSConnection, suspension *model.UserSuspension) {
+	payload, err := json.Marshal(newSuspensionInfo(suspension))
+	if err != nil {
+		return
+	}
+	if data, err := json.Marshal(WSMessage{Type: "suspended", Payload: payload}); err == nil {
+		wsConn.writeMessage(data)
+	}
+}
+
+// suspendUserHandler 管理员直接封禁用户
+func (s *HTTPServer) suspendUserHandler(c *gin.Context) {
+	adminUID := c.GetString("user_id")
+	targetUID := c.Param("uid")
+
+	var req suspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxHandleNoteLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "封禁原因为1~200个字符"})
+		return
+	}
+	expiresAt, err := parseSuspendDuration(req.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var target model.User
+	if err := db.Select("uid, role").Where("uid = ?", targetUID).First(&target).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		}
+		return
+	}
+	if target.Role == model.UserRoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能封禁管理员"})
+		return
+	}
+
+	var suspension *model.UserSuspension
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if suspension, err = createSuspension(tx, targetUID, adminUID, req.Reason, req.ReadOnly, expiresAt, nil); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, adminUID, model.AuditActionSuspendUser, model.ReportTargetUser, targetUID, nil, map[string]interface{}{
+			"suspension_id": suspension.SuspensionID,
+			"reason":        req.Reason,
+			"read_only":     req.ReadOnly,
+			"expires_at":    formatOptionalTime(expiresAt),
+		})
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "封禁失败"})
+		return
+	}
+	s.ws.NotifySuspended(targetUID, suspension)
+
+	c.JSON(http.StatusOK, suspension)
+}
+
+// liftSuspensionHandler 管理员提前解除用户当前的全部封禁
+func liftSuspensionHandler(c *gin.Context) {
+	adminUID := c.GetString("user_id")
+	targetUID := c.Param("uid")
+
+	var req liftSuspensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(req.Note) > maxHandleNoteLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "处理说明不能超过200个字符"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var lifted int64
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var ids []int64
+		if err := tx.Model(&model.UserSuspension{}).
+			Where("uid = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", targetUID, now).
+			Pluck("suspension_id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		result := tx.Model(&model.UserSuspension{}).Where("suspension_id IN ?", ids).
+			Updates(map[string]interface{}{"lifted_at": now, "lifted_by": adminUID})
+		if result.Error != nil {
+			return result.Error
+		}
+		lifted = result.RowsAffected
+		return writeAuditLog(tx, adminUID, model.AuditActionLiftSuspension, model.ReportTargetUser, targetUID, nil, map[string]interface{}{
+			"suspension_ids": ids,
+			"note":           req.Note,
+		})
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解除封禁失败"})
+		return
+	}
+	if lifted == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该用户当前没有封禁"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已解除封禁", "lifted": lifted})
+}
+
+// getUserSanctionsHandler 管理员查看用户的警告和封禁记录
+func getUserSanctionsHandler(c *gin.Context) {
+	targetUID := c.Param("uid")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var warnings []model.UserWarning
+	if err := db.Where("uid = ?", targetUID).Order("created_at DESC").Find(&warnings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取警告记录失败"})
+		return
+	}
+	var suspensions []model.UserSuspension
+	if err := db.Where("uid = ?", targetUID).Order("created_at DESC").Find(&suspensions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取封禁记录失败"})
+		return
+	}
+	active, err := activeSuspension(db, targetUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取封禁记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"warnings":    warnings,
+		"suspensions": suspensions,
+		"active":      active,
+	})
+}
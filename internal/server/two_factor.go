@@ -0,0 +1,423 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"NetherLink-server/pkg/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount = 10
+	totpSkew          = 1 // 允许前后各一个时间步的时钟误差
+
+	defaultChallengeExpire = 5 * time.Minute
+)
+
+// 登录失败原因：两步验证码错误
+const loginReasonWrong2FA = "wrong_2fa_code"
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type disableTwoFactorRequest struct {
+	Passwd string `json:"passwd" binding:"required"`
+	Code   string `json:"code" binding:"required"` // TOTP验证码或恢复码
+}
+
+type twoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP验证码或恢复码
+}
+
+// twoFactorEnabled 用户是否已开启两步验证
+func twoFactorEnabled(db *gorm.DB, uid string) (bool, error) {
+	var count int64
+	if err := db.Model(&model.UserTOTP{}).Where("uid = ? AND enabled = ?", uid, true).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// challengeExpire 二次验证的有效期，未配置时使用默认值
+func challengeExpire() time.Duration {
+	if expire := config.GlobalConfig.TwoFactor.ChallengeExpire; expire > 0 {
+		return expire
+	}
+	return defaultChallengeExpire
+}
+
+// generateChallengeToken 密码校验通过后签发的临时token，只能用于换取正式登录令牌
+func generateChallengeToken(uid string) (string, error) {
+	claims := jwt.MapClaims{
+		"uid": uid,
+		"typ": "2fa_challenge",
+		"iss": "netherlink",
+		"exp": time.Now().Add(challengeExpire()).Unix(),
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString([]byte(config.GlobalConfig.JWT.Secret))
+}
+
+func parseChallengeToken(tokenString string) (string, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.GlobalConfig.JWT.Secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil || !token.Valid {
+		return "", errInvalidToken
+	}
+	if typ, _ := claims["typ"].(string); typ != "2fa_challenge" {
+		return "", errInvalidToken
+	}
+	uid, ok := claims["uid"].(string)
+	if !ok {
+		return "", errInvalidToken
+	}
+	return uid, nil
+}
+
+// verifySecondFactor 校验TOTP验证码，不匹配时尝试作为恢复码使用
+func verifySecondFactor(db *gorm.DB, uid, code string) (bool, error) {
+	var totp model.UserTOTP
+	if err := db.Where("uid = ? AND enabled = ?", uid, true).First(&totp).Error; err != nil {
+		return false, err
+	}
+
+	if step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now(), totpSkew); ok {
+		// 同一个时间步的验证码只能使用一次
+		result := db.Model(&model.UserTOTP{}).
+			Where("uid = ? AND last_used_step < ?", uid, step).
+			Update("last_used_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		return result.RowsAffected > 0, nil
+	}
+
+	result := db.Model(&model.RecoveryCode{}).
+		Where("uid = ? AND code_hash = ? AND used_at IS NULL", uid, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// generateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func generateRecoveryCodes(tx *gorm.DB, uid string) ([]string, error) {
+	if err := tx.Where("uid = ?", uid).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	now := time.Now()
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+		if err := tx.Create(&model.RecoveryCode{
+			UID:       uid,
+			CodeHash:  hashRecoveryCode(code),
+			CreatedAt: now,
+		}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode 恢复码忽略大小写和分隔符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return hashToken(code)
+}
+
+// getTwoFactorStatusHandler 查询两步验证状态
+func getTwoFactorStatusHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	enabled, err := twoFactorEnabled(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询两步验证状态失败"})
+		return
+	}
+
+	var remaining int64
+	if enabled {
+		db.Model(&model.RecoveryCode{}).Where("uid = ? AND used_at IS NULL", userID).Count(&remaining)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  enabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// setupTwoFactorHandler 生成TOTP密钥，需再调用确认接口后才会生效
+func setupTwoFactorHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	enabled, err := twoFactorEnabled(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询两步验证状态失败"})
+		return
+	}
+	if enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "已开启两步验证"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+
+	// 未确认的密钥直接覆盖
+	totp := model.UserTOTP{
+		UID:       userID,
+		Secret:    secret,
+		Enabled:   false,
+		CreatedAt: time.Now(),
+	}
+	if err := db.Save(&totp).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存密钥失败"})
+		return
+	}
+
+	issuer := config.GlobalConfig.TwoFactor.Issuer
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": utils.GetTOTPURI(issuer, user.Email, secret),
+	})
+}
+
+// confirmTwoFactorHandler 用验证器App生成的验证码确认开启两步验证，返回一次性恢复码
+func confirmTwoFactorHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var totp model.UserTOTP
+	if err := db.Where("uid = ?", userID).First(&totp).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先生成两步验证密钥"})
+		return
+	}
+	if totp.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "已开启两步验证"})
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(totp.Secret, req.Code, time.Now(), totpSkew)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	var codes []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&totp).Updates(map[string]interface{}{
+			"enabled":        true,
+			"enabled_at":     now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		codes, err = generateRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "开启两步验证失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "两步验证已开启",
+		"recovery_codes": codes,
+	})
+}
+
+// regenerateRecoveryCodesHandler 重新生成恢复码
+func regenerateRecoveryCodesHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	ok, err := verifySecondFactor(db, userID, req.Code)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "校验验证码失败"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误或未开启两步验证"})
+		return
+	}
+
+	var codes []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		codes, err = generateRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成恢复码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// disableTwoFactorHandler 关闭两步验证，需要密码和验证码
+func disableTwoFactorHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req disableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var count int64
+	db.Model(&model.User{}).Where("uid = ? AND password = ?", userID, hashPassword(req.Passwd)).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密码错误"})
+		return
+	}
+
+	ok, err := verifySecondFactor(db, userID, req.Code)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "校验验证码失败"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误或未开启两步验证"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uid = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("uid = ?", userID).Delete(&model.UserTOTP{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "关闭两步验证失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "两步验证已关闭"})
+}
+
+// twoFactorLoginHandler 用登录时返回的challenge token和验证码换取正式登录令牌
+func twoFactorLoginHandler(c *gin.Context) {
+	var req twoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	uid, err := parseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证已过期，请重新登录"})
+		return
+	}
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("uid = ?", uid).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证已过期，请重新登录"})
+		return
+	}
+
+	// 两步验证码同样计入登录失败次数
+	if wait := loginLimiter.lockedFor(user.Email, c.ClientIP()); wait > 0 {
+		recordLogin(c, &user, user.Email, false, loginReasonLocked)
+		respondLoginLocked(c, wait)
+		return
+	}
+
+	ok, err := verifySecondFactor(db, uid, req.Code)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "校验验证码失败"})
+		return
+	}
+	if !ok {
+		failures, alert := loginLimiter.fail(user.Email, c.ClientIP())
+		recordLogin(c, &user, user.Email, false, loginReasonWrong2FA)
+		if alert {
+			sendLoginAlert(user.Email, c.ClientIP(), failures)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+	if rejectSuspendedLogin(c, db, &user) {
+		return
+	}
+
+	respondLoginSuccess(c, db, &user)
+}
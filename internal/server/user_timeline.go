@@ -0,0 +1,100 @@
+package server
+
+import (
+	"NetherLink-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// UserPostStats 个人主页展示的动态统计
+type UserPostStats struct {
+	PostCount     int64 `json:"post_count"`     // 当前用户可见的动态数
+	LikesReceived int64 `json:"likes_received"` // 当前用户可见的动态累计获得的点赞数
+	FriendCount   int64 `json:"friend_count"`
+}
+
+// loadUserPostStats 统计author的动态数、获赞数和好友数，动态数和获赞数按viewer的可见范围计算
+func loadUserPostStats(db *gorm.DB, viewerUID, authorUID string) (*UserPostStats, error) {
+	var stats UserPostStats
+	if err := db.Table("posts").
+		Scopes(visiblePostsScope(viewerUID)).
+		Where("posts.user_id = ? AND posts.deleted_at IS NULL", authorUID).
+		Count(&stats.PostCount).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Table("post_likes").
+		Joins("INNER JOIN posts ON posts.post_id = post_likes.post_id").
+		Scopes(visiblePostsScope(viewerUID)).
+		Where("posts.user_id = ? AND posts.deleted_at IS NULL", authorUID).
+		Count(&stats.LikesReceived).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&model.Friend{}).Where("user_id = ?", authorUID).Count(&stats.FriendCount).Error; err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// getUserPostsHandler 获取指定用户的动态列表，按可见范围过滤并附带主页统计
+func getUserPostsHandler(c *gin.Context) {
+	userID := c.GetString("user_id")
+	targetUID := c.Param("uid")
+
+	cursor, err := parseFeedCursor(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+	limit := parseFeedLimit(c)
+
+	db, err := getDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "数据库连接失败"})
+		return
+	}
+
+	var author model.User
+	if err := db.Where("uid = ?", targetUID).First(&author).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"code": -1, "message": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取用户信息失败"})
+		}
+		return
+	}
+
+	stats, err := loadUserPostStats(db, userID, targetUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取动态统计失败"})
+		return
+	}
+
+	// 作者设置了动态不可见时只返回统计，不返回列表
+	visible, err := canViewPosts(db, userID, targetUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子列表失败"})
+		return
+	}
+	var result []model.PostPreview
+	var next *feedCursor
+	if visible {
+		var rows []feedRow
+		query := db.Table("posts").Scopes(visiblePostsScope(userID)).Where("posts.user_id = ?", targetUID)
+		if rows, next, err = queryFeedPage(query, cursor, limit); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子列表失败"})
+			return
+		}
+		if result, err = buildPostPreviews(db, userID, rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": "获取帖子统计失败"})
+			return
+		}
+	} else {
+		result = []model.PostPreview{}
+	}
+
+	resp := feedResponse(result, next)
+	resp["stats"] = stats
+	resp["posts_hidden"] = !visible
+	c.JSON(http.StatusOK, resp)
+}
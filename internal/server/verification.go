@@ -0,0 +1,90 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/pkg/utils"
+	"crypto/rand"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// 验证码用途，不同用途的验证码互相独立，不能混用
+const (
+	codePurposeRegister      = "register"
+	codePurposeResetPassword = "reset_password"
+	codePurposeChangeEmail   = "change_email"
+	codePurposeDeleteAccount = "delete_account"
+)
+
+const defaultCodeTTL = 3 * time.Minute
+
+// codeMail 各用途验证码邮件的标题和模板
+type codeMail struct {
+	Subject  string
+	Template func(code string, ttl time.Duration) string
+}
+
+var codeMails = map[string]codeMail{
+	codePurposeRegister:      {Subject: "NetherLink 注册验证码", Template: utils.GetEmailTemplate},
+	codePurposeResetPassword: {Subject: "NetherLink 重置密码验证码", Template: utils.GetResetPasswordEmailTemplate},
+	codePurposeChangeEmail:   {Subject: "NetherLink 更换邮箱验证码", Template: utils.GetChangeEmailTemplate},
+	codePurposeDeleteAccount: {Subject: "NetherLink 注销账号验证码", Template: utils.GetDeleteAccountEmailTemplate},
+}
+
+// codeStore 全局验证码存储，在 NewHTTPServer 中按配置初始化
+var codeStore VerificationStore
+
+// codeTTL 验证码有效期，未配置时使用默认值
+func codeTTL() time.Duration {
+	if ttl := config.GlobalConfig.Verification.CodeTTL; ttl > 0 {
+		return ttl
+	}
+	return defaultCodeTTL
+}
+
+// generateCode 使用 crypto/rand 生成6位数字验证码，避免验证码可被预测
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(900000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()+100000), nil
+}
+
+func codeKey(purpose, email string) string {
+	return purpose + ":" + email
+}
+
+// sendVerificationCode 生成指定用途的验证码并发送到邮箱
+func sendVerificationCode(purpose, email, ip string) error {
+	mail := codeMails[purpose]
+
+	code, err := codeStore.Issue(purpose, email, ip)
+	if err != nil {
+		return err
+	}
+
+	emailCfg := config.GlobalConfig.Email
+	sender := utils.NewEmailSender(emailCfg.SMTPHost, emailCfg.SMTPPort, emailCfg.Sender, emailCfg.DisplayName, emailCfg.Password, emailCfg.UseSSL)
+	return sender.Send(email, mail.Subject, mail.Template(code, codeTTL()))
+}
+
+// verifyCode 校验验证码，校验通过后验证码立即作废
+func verifyCode(purpose, email, code string) error {
+	return codeStore.Verify(purpose, email, code)
+}
+
+// respondCodeError 将验证码相关的错误写入响应
+func respondCodeError(c *gin.Context, err error) {
+	switch err {
+	case ErrCodeCooldown:
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case ErrCodeInvalid, ErrCodeTooManyAttempts:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "验证码服务异常"})
+	}
+}
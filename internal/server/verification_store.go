@@ -0,0 +1,287 @@
+package server
+
+import (
+	"NetherLink-server/config"
+	"NetherLink-server/internal/model"
+	"crypto/subtle"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCodeCooldown        = errors.New("验证码发送过于频繁，请稍后再试")
+	ErrCodeInvalid         = errors.New("验证码无效或已过期")
+	ErrCodeTooManyAttempts = errors.New("验证码错误次数过多，请重新获取")
+)
+
+// VerificationStore 邮箱验证码存储
+type VerificationStore interface {
+	// Issue 为指定用途和邮箱生成新验证码（覆盖旧验证码），受邮箱和IP的发送冷却限制
+	Issue(purpose, email, ip string) (string, error)
+	// Verify 校验验证码，通过后验证码立即作废；失败次数达到上限后验证码失效
+	Verify(purpose, email, code string) error
+	// Sweep 清理已过期的验证码和冷却记录
+	Sweep() error
+}
+
+// newVerificationStore 根据配置创建验证码存储，并启动后台清理
+func newVerificationStore(cfg config.VerificationConfig) VerificationStore {
+	if cfg.CodeTTL <= 0 {
+		cfg.CodeTTL = defaultCodeTTL
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.SweepInterval <= 0 {
+		cfg.SweepInterval = time.Minute
+	}
+
+	var store VerificationStore
+	if cfg.Store == "database" {
+		store = &dbVerificationStore{cfg: cfg}
+	} else {
+		store = &memoryVerificationStore{
+			cfg:       cfg,
+			codes:     make(map[string]*memoryCodeEntry),
+			emailSent: make(map[string]time.Time),
+			ipSent:    make(map[string]time.Time),
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.SweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := store.Sweep(); err != nil {
+				log.Printf("清理过期验证码失败: %v", err)
+			}
+		}
+	}()
+
+	return store
+}
+
+// maxCooldown 冷却记录至少要保留这么久
+func maxCooldown(cfg config.VerificationConfig) time.Duration {
+	if cfg.EmailCooldown > cfg.IPCooldown {
+		return cfg.EmailCooldown
+	}
+	return cfg.IPCooldown
+}
+
+func codeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// memoryVerificationStore 内存存储，重启后验证码丢失，适合单机部署
+type memoryVerificationStore struct {
+	cfg       config.VerificationConfig
+	mu        sync.Mutex
+	codes     map[string]*memoryCodeEntry // key: purpose:email
+	emailSent map[string]time.Time
+	ipSent    map[string]time.Time
+}
+
+type memoryCodeEntry struct {
+	Code      string
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+func (m *memoryVerificationStore) Issue(purpose, email, ip string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := m.emailSent[email]; ok && now.Sub(last) < m.cfg.EmailCooldown {
+		return "", ErrCodeCooldown
+	}
+	if last, ok := m.ipSent[ip]; ok && now.Sub(last) < m.cfg.IPCooldown {
+		return "", ErrCodeCooldown
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return "", err
+	}
+	m.codes[codeKey(purpose, email)] = &memoryCodeEntry{
+		Code:      code,
+		ExpiresAt: now.Add(m.cfg.CodeTTL),
+	}
+	m.emailSent[email] = now
+	m.ipSent[ip] = now
+	return code, nil
+}
+
+func (m *memoryVerificationStore) Verify(purpose, email, code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := codeKey(purpose, email)
+	entry, ok := m.codes[key]
+	if !ok || time.Now().After(entry.ExpiresAt) {
+		return ErrCodeInvalid
+	}
+	if entry.Attempts >= m.cfg.MaxAttempts {
+		delete(m.codes, key)
+		return ErrCodeTooManyAttempts
+	}
+	if !codeEqual(entry.Code, code) {
+		entry.Attempts++
+		if entry.Attempts >= m.cfg.MaxAttempts {
+			delete(m.codes, key)
+			return ErrCodeTooManyAttempts
+		}
+		return ErrCodeInvalid
+	}
+
+	delete(m.codes, key)
+	return nil
+}
+
+func (m *memoryVerificationStore) Sweep() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range m.codes {
+		if now.After(entry.ExpiresAt) {
+			delete(m.codes, key)
+		}
+	}
+
+	cooldown := maxCooldown(m.cfg)
+	for email, t := range m.emailSent {
+		if now.Sub(t) >= cooldown {
+			delete(m.emailSent, email)
+		}
+	}
+	for ip, t := range m.ipSent {
+		if now.Sub(t) >= cooldown {
+			delete(m.ipSent, ip)
+		}
+	}
+	return nil
+}
+
+// dbVerificationStore 数据库存储，重启不丢失，多实例部署时可共享
+type dbVerificationStore struct {
+	cfg config.VerificationConfig
+}
+
+func (d *dbVerificationStore) Issue(purpose, email, ip string) (string, error) {
+	db, err := getDB()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	var count int64
+	if d.cfg.EmailCooldown > 0 {
+		if err := db.Model(&model.VerificationCode{}).
+			Where("email = ? AND created_at > ?", email, now.Add(-d.cfg.EmailCooldown)).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return "", ErrCodeCooldown
+		}
+	}
+	if d.cfg.IPCooldown > 0 {
+		if err := db.Model(&model.VerificationCode{}).
+			Where("ip = ? AND created_at > ?", ip, now.Add(-d.cfg.IPCooldown)).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return "", ErrCodeCooldown
+		}
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return "", err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 同一用途只保留最新的验证码
+		if err := tx.Model(&model.VerificationCode{}).
+			Where("purpose = ? AND email = ? AND used_at IS NULL", purpose, email).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.VerificationCode{
+			Purpose:   purpose,
+			Email:     email,
+			CodeHash:  hashToken(code),
+			IP:        ip,
+			ExpiresAt: now.Add(d.cfg.CodeTTL),
+			CreatedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+func (d *dbVerificationStore) Verify(purpose, email, code string) error {
+	db, err := getDB()
+	if err != nil {
+		return err
+	}
+
+	var entry model.VerificationCode
+	if err := db.Where("purpose = ? AND email = ? AND used_at IS NULL AND expires_at > ?", purpose, email, time.Now()).
+		Order("id DESC").
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCodeInvalid
+		}
+		return err
+	}
+
+	// 先以 attempts < MaxAttempts 为条件占用一次尝试机会，避免并发请求绕过次数限制
+	claim := db.Model(&model.VerificationCode{}).
+		Where("id = ? AND attempts < ?", entry.ID, d.cfg.MaxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return ErrCodeTooManyAttempts
+	}
+
+	if !codeEqual(entry.CodeHash, hashToken(code)) {
+		if entry.Attempts+1 >= d.cfg.MaxAttempts {
+			return ErrCodeTooManyAttempts
+		}
+		return ErrCodeInvalid
+	}
+
+	// 以 used_at IS NULL 为条件更新，保证并发请求下只有一次校验成功
+	result := db.Model(&model.VerificationCode{}).
+		Where("id = ? AND used_at IS NULL AND attempts <= ?", entry.ID, d.cfg.MaxAttempts).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCodeInvalid
+	}
+	return nil
+}
+
+func (d *dbVerificationStore) Sweep() error {
+	db, err := getDB()
+	if err != nil {
+		return err
+	}
+
+	// 冷却期内的记录仍需保留，用于限制发送频率
+	now := time.Now()
+	return db.Where("expires_at < ? AND created_at < ?", now, now.Add(-maxCooldown(d.cfg))).
+		Delete(&model.VerificationCode{}).Error
+}
@@ -1,15 +1,15 @@
 package server
 
 import (
-	"NetherLink-server/config"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 	"NetherLink-server/pkg/database"
@@ -21,7 +21,16 @@ type WSConnection struct {
 	conn      *websocket.Conn
 	isAuth    bool
 	uid       string
+	sessionID string
 	authTimer *time.Timer
+	writeMu   sync.Mutex
+}
+
+// writeMessage 发送文本消息，gorilla/websocket 不允许并发写，需要加锁
+func (c *WSConnection) writeMessage(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, data)
 }
 
 // WSServer WebSocket服务器结构
@@ -60,8 +69,9 @@ type FriendRequestPayload struct {
 
 // FriendRequestResponse 好友请求的响应结构
 type FriendRequestResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	AutoAccepted bool   `json:"auto_accepted"` // 对方设置了自动通过，已直接成为好友
 }
 
 // FriendRequestNotification 好友请求的通知结构
@@ -183,7 +193,7 @@ func (s *WSServer) handleWebSocket(c *gin.Context) {
 	wsConn.authTimer = time.AfterFunc(10*time.Second, func() {
 		if !wsConn.isAuth {
 			log.Printf("连接超时未登录，断开连接")
-			conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"error","payload":{"message":"登录超时"}}`))
+			wsConn.writeMessage([]byte(`{"type":"error","payload":{"message":"登录超时"}}`))
 			conn.Close()
 		}
 	})
@@ -199,7 +209,8 @@ func (s *WSServer) handleConnection(wsConn *WSConnection) {
 			wsConn.authTimer.Stop()
 		}
 		if wsConn.uid != "" {
-			s.connections.Delete(wsConn.uid)
+			// 被顶号的旧连接退出时不能删掉新连接
+			s.connections.CompareAndDelete(wsConn.uid, wsConn)
 		}
 	}()
 
@@ -265,27 +276,36 @@ func (s *WSServer) handleLogin(wsConn *WSConnection, payload json.RawMessage) er
 		return errors.New("无效的登录信息")
 	}
 
-	// 验证token
-	claims := jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(loginPayload.Token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.GlobalConfig.JWT.Secret), nil
-	})
-
-	if err != nil || !token.Valid {
+	// 验证token及其会话状态
+	uid, sessionID, err := parseAccessToken(loginPayload.Token)
+	if err != nil {
 		return errors.New("认证失败")
 	}
 
 	// 验证uid
-	uid, ok := claims["uid"].(string)
-	if !ok || uid != loginPayload.UID {
+	if uid != loginPayload.UID {
 		return errors.New("认证失败")
 	}
 
+	// 完全封禁的账号拒绝连接
+	db, err := database.GetDB()
+	if err != nil {
+		return errors.New("数据库连接失败")
+	}
+	suspension, err := activeSuspension(db, uid)
+	if err != nil {
+		return errors.New("查询账号状态失败")
+	}
+	if suspension != nil && !suspension.ReadOnly {
+		s.sendSuspended(wsConn, suspension)
+		return errors.New(suspensionMessage(suspension))
+	}
+
 	// 处理重复登录
 	if oldConn, loaded := s.connections.LoadOrStore(uid, wsConn); loaded {
 		if oldWsConn, ok := oldConn.(*WSConnection); ok {
 			// 发送被踢下线消息
-			oldWsConn.conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"error","payload":{"message":"账号在其他设备登录"}}`))
+			oldWsConn.writeMessage([]byte(`{"type":"error","payload":{"message":"账号在其他设备登录"}}`))
 			oldWsConn.conn.Close()
 		}
 		s.connections.Store(uid, wsConn)
@@ -294,10 +314,11 @@ func (s *WSServer) handleLogin(wsConn *WSConnection, payload json.RawMessage) er
 	// 设置连接状态
 	wsConn.isAuth = true
 	wsConn.uid = uid
+	wsConn.sessionID = sessionID
 	wsConn.authTimer.Stop()
 
 	// 发送登录成功消息
-	wsConn.conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"login_success"}`))
+	wsConn.writeMessage([]byte(`{"type":"login_success"}`))
 	return nil
 }
 
@@ -309,19 +330,75 @@ func (s *WSServer) handleChat(wsConn *WSConnection, payload json.RawMessage) err
 	}
 
 	// 验证必要字段
-	if chatPayload.To == "" || chatPayload.Content == "" {
+	if chatPayload.To == "" {
 		return errors.New("缺少必要字段")
 	}
 
-	// 仅处理文本消息
-	if chatPayload.Type != "text" {
+	db, err := database.GetDB()
+	if err != nil {
+		return errors.New("数据库连接失败")
+	}
+
+	// 只读封禁期间不能发送消息
+	if suspension, err := activeSuspension(db, wsConn.uid); err != nil {
+		return errors.New("查询账号状态失败")
+	} else if suspension != nil {
+		return errors.New(suspensionMessage(suspension))
+	}
+
+	// 支持文本消息和动态分享
+	var sharedPostID int64
+	switch chatPayload.Type {
+	case "text":
+		if chatPayload.Content == "" {
+			return errors.New("缺少必要字段")
+		}
+	case messageTypePostShare:
+		if sharedPostID, err = preparePostShare(db, wsConn.uid, &chatPayload); err != nil {
+			return err
+		}
+	default:
 		return errors.New("暂不支持的消息类型")
 	}
 
-	// 生成消息ID（使用时间戳和随机数）
-	messageID := time.Now().UnixNano()
+	hits, err := moderateFields(&chatPayload.Content)
+	if err != nil {
+		return err
+	}
+
+	if chatPayload.IsGroup {
+		return s.handleGroupChat(wsConn, &chatPayload, sharedPostID, hits)
+	}
+
+	// extra 列为JSON类型，客户端传入的附加信息必须是合法JSON
+	extra := chatPayload.Extra
+	if extra == "" {
+		extra = "{}"
+	} else if !json.Valid([]byte(extra)) {
+		return errors.New("无效的消息附加信息")
+	}
+
+	// 保存消息，使用数据库ID作为消息ID
 	timestamp := time.Now()
+	message := model.PrivateMessage{
+		ReceiverID: chatPayload.To,
+		SenderID:   wsConn.uid,
+		Timestamp:  timestamp,
+		Type:       chatPayload.Type,
+		Content:    chatPayload.Content,
+		Extra:      extra,
+	}
+	if err := db.Create(&message).Error; err != nil {
+		return errors.New("保存消息失败")
+	}
+	messageID := message.ID
 	conversationID := getConversationID(wsConn.uid, chatPayload.To)
+	flagForReview(db, model.ModerationScenePrivateMessage, strconv.FormatInt(messageID, 10), wsConn.uid, message.Content, hits)
+	if sharedPostID != 0 {
+		if err := incrementShareCount(db, sharedPostID); err != nil {
+			log.Printf("更新分享次数失败: %v", err)
+		}
+	}
 
 	// 准备响应消息
 	response := ChatResponse{
@@ -353,7 +430,7 @@ func (s *WSServer) handleChat(wsConn *WSConnection, payload json.RawMessage) err
 		return errors.New("生成响应消息失败")
 	}
 	
-	if err := wsConn.conn.WriteMessage(websocket.TextMessage, responseBytes); err != nil {
+	if err := wsConn.writeMessage(responseBytes); err != nil {
 		return errors.New("发送响应消息失败")
 	}
 
@@ -376,14 +453,12 @@ func (s *WSServer) handleChat(wsConn *WSConnection, payload json.RawMessage) err
 				return errors.New("生成接收者消息失败")
 			}
 			
-			if err := wsReceiver.conn.WriteMessage(websocket.TextMessage, receiverBytes); err != nil {
+			if err := wsReceiver.writeMessage(receiverBytes); err != nil {
 				return errors.New("发送消息给接收者失败")
 			}
 		}
 	}
 
-	// TODO: 将消息保存到数据库（这部分代码保持不变，等待数据库结构确定后实现）
-
 	return nil
 }
 
@@ -416,6 +491,16 @@ func (s *WSServer) handleFriendRequest(wsConn *WSConnection, payload json.RawMes
 		return errors.New("用户不存在")
 	}
 
+	// 检查对方的好友申请设置
+	privacy, err := loadPrivacy(db, requestPayload.ToUID)
+	if err != nil {
+		return errors.New("获取用户设置失败")
+	}
+	if privacy.FriendPolicy == model.FriendPolicyDisabled {
+		return errors.New("对方不接受好友申请")
+	}
+	autoAccept := privacy.FriendPolicy == model.FriendPolicyAutoAccept
+
 	// 检查是否已经是好友
 	var existingFriend model.Friend
 	if err := db.Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)",
@@ -445,7 +530,7 @@ func (s *WSServer) handleFriendRequest(wsConn *WSConnection, payload json.RawMes
 		return errors.New("获取用户信息失败")
 	}
 
-	// 创建好友请求
+	// 创建好友请求，对方设置了自动通过时直接建立好友关系
 	friendReq := model.FriendRequest{
 		FromUID:   wsConn.uid,
 		ToUID:     requestPayload.ToUID,
@@ -454,8 +539,22 @@ func (s *WSServer) handleFriendRequest(wsConn *WSConnection, payload json.RawMes
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	if autoAccept {
+		friendReq.Status = "accepted"
+	}
 
-	if err := db.Create(&friendReq).Error; err != nil {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&friendReq).Error; err != nil {
+			return err
+		}
+		if !autoAccept {
+			return nil
+		}
+		return tx.Create([]model.Friend{
+			{UserID: wsConn.uid, FriendID: requestPayload.ToUID, CreatedAt: time.Now()},
+			{UserID: requestPayload.ToUID, FriendID: wsConn.uid, CreatedAt: time.Now()},
+		}).Error
+	}); err != nil {
 		return errors.New("创建好友请求失败")
 	}
 
@@ -464,13 +563,17 @@ func (s *WSServer) handleFriendRequest(wsConn *WSConnection, payload json.RawMes
 		Success: true,
 		Message: "好友请求已发送",
 	}
+	if autoAccept {
+		response.Message = "已添加为好友"
+		response.AutoAccepted = true
+	}
 	responseData, _ := json.Marshal(response)
 	responseMsg := WSMessage{
 		Type:    "friend_request_response",
 		Payload: responseData,
 	}
 	responseBytes, _ := json.Marshal(responseMsg)
-	wsConn.conn.WriteMessage(websocket.TextMessage, responseBytes)
+	wsConn.writeMessage(responseBytes)
 
 	// 如果接收者在线，发送通知
 	if receiverConn, ok := s.connections.Load(requestPayload.ToUID); ok {
@@ -483,13 +586,17 @@ func (s *WSServer) handleFriendRequest(wsConn *WSConnection, payload json.RawMes
 				Message:    requestPayload.Message,
 				CreatedAt:  friendReq.CreatedAt.Format("2006-01-02 15:04:05"),
 			}
+			notificationType := "friend_request_received"
+			if autoAccept {
+				notificationType = "friend_added"
+			}
 			notificationData, _ := json.Marshal(notification)
 			notificationMsg := WSMessage{
-				Type:    "friend_request_received",
+				Type:    notificationType,
 				Payload: notificationData,
 			}
 			notificationBytes, _ := json.Marshal(notificationMsg)
-			wsReceiver.conn.WriteMessage(websocket.TextMessage, notificationBytes)
+			wsReceiver.writeMessage(notificationBytes)
 		}
 	}
 
@@ -607,7 +714,7 @@ func (s *WSServer) handleFriendRequestResponse(wsConn *WSConnection, payload jso
 		Payload: responseData,
 	}
 	responseBytes, _ := json.Marshal(responseMsg)
-	wsConn.conn.WriteMessage(websocket.TextMessage, responseBytes)
+	wsConn.writeMessage(responseBytes)
 
 	// 发送通知给申请者
 	notification := FriendRequestResultNotification{
@@ -627,7 +734,7 @@ func (s *WSServer) handleFriendRequestResponse(wsConn *WSConnection, payload jso
 	// 如果申请者在线，发送通知
 	if receiverConn, ok := s.connections.Load(friendRequest.FromUID); ok {
 		if wsReceiver, ok := receiverConn.(*WSConnection); ok {
-			wsReceiver.conn.WriteMessage(websocket.TextMessage, notificationBytes)
+			wsReceiver.writeMessage(notificationBytes)
 		}
 	}
 
@@ -711,7 +818,7 @@ func (s *WSServer) handleGroupJoinRequest(wsConn *WSConnection, payload json.Raw
 		Payload: responseData,
 	}
 	responseBytes, _ := json.Marshal(responseMsg)
-	wsConn.conn.WriteMessage(websocket.TextMessage, responseBytes)
+	wsConn.writeMessage(responseBytes)
 
 	// 获取群主和管理员列表
 	var admins []model.GroupMember
@@ -739,7 +846,7 @@ func (s *WSServer) handleGroupJoinRequest(wsConn *WSConnection, payload json.Raw
 	for _, admin := range admins {
 		if receiverConn, ok := s.connections.Load(admin.UID); ok {
 			if wsReceiver, ok := receiverConn.(*WSConnection); ok {
-				wsReceiver.conn.WriteMessage(websocket.TextMessage, notificationBytes)
+				wsReceiver.writeMessage(notificationBytes)
 			}
 		}
 	}
@@ -845,7 +952,7 @@ func (s *WSServer) handleGroupJoinRequestResponse(wsConn *WSConnection, payload
 		Payload: responseData,
 	}
 	responseBytes, _ := json.Marshal(responseMsg)
-	wsConn.conn.WriteMessage(websocket.TextMessage, responseBytes)
+	wsConn.writeMessage(responseBytes)
 
 	// 准备通知消息
 	notification := GroupJoinRequestResultNotification{
@@ -868,7 +975,7 @@ func (s *WSServer) handleGroupJoinRequestResponse(wsConn *WSConnection, payload
 	// 通知申请者
 	if receiverConn, ok := s.connections.Load(groupRequest.UserID); ok {
 		if wsReceiver, ok := receiverConn.(*WSConnection); ok {
-			wsReceiver.conn.WriteMessage(websocket.TextMessage, notificationBytes)
+			wsReceiver.writeMessage(notificationBytes)
 		}
 	}
 
@@ -888,7 +995,7 @@ func (s *WSServer) handleGroupJoinRequestResponse(wsConn *WSConnection, payload
 	for _, admin := range otherAdmins {
 		if receiverConn, ok := s.connections.Load(admin.UID); ok {
 			if wsReceiver, ok := receiverConn.(*WSConnection); ok {
-				wsReceiver.conn.WriteMessage(websocket.TextMessage, notificationBytes)
+				wsReceiver.writeMessage(notificationBytes)
 			}
 		}
 	}
@@ -912,7 +1019,7 @@ func (s *WSServer) sendError(wsConn *WSConnection, message string) {
 	}
 	
 	if data, err := json.Marshal(response); err == nil {
-		wsConn.conn.WriteMessage(websocket.TextMessage, data)
+		wsConn.writeMessage(data)
 	}
 }
 
@@ -920,17 +1027,58 @@ func (s *WSServer) sendError(wsConn *WSConnection, message string) {
 func (s *WSServer) SendMessage(uid string, message []byte) error {
 	if conn, ok := s.connections.Load(uid); ok {
 		if wsConn, ok := conn.(*WSConnection); ok {
-			return wsConn.conn.WriteMessage(websocket.TextMessage, message)
+			return wsConn.writeMessage(message)
 		}
 	}
 	return errors.New("用户未连接")
 }
 
+// PushEvent 向一组用户推送事件，不在线的用户直接跳过
+func (s *WSServer) PushEvent(uids []string, eventType string, payload interface{}) {
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("生成推送消息失败: %v", err)
+		return
+	}
+	msgBytes, err := json.Marshal(WSMessage{
+		Type:    eventType,
+		Payload: payloadData,
+	})
+	if err != nil {
+		log.Printf("生成推送消息失败: %v", err)
+		return
+	}
+
+	for _, uid := range uids {
+		s.SendMessage(uid, msgBytes)
+	}
+}
+
+// DisconnectSession 断开指定会话的在线连接（会话被吊销时调用）
+func (s *WSServer) DisconnectSession(uid, sessionID, reason string) {
+	if conn, ok := s.connections.Load(uid); ok {
+		if wsConn, ok := conn.(*WSConnection); ok && wsConn.sessionID == sessionID {
+			s.sendError(wsConn, reason)
+			wsConn.conn.Close()
+		}
+	}
+}
+
+// DisconnectUser 断开用户的在线连接
+func (s *WSServer) DisconnectUser(uid, reason string) {
+	if conn, ok := s.connections.Load(uid); ok {
+		if wsConn, ok := conn.(*WSConnection); ok {
+			s.sendError(wsConn, reason)
+			wsConn.conn.Close()
+		}
+	}
+}
+
 // BroadcastMessage 广播消息给所有已认证的用户
 func (s *WSServer) BroadcastMessage(message []byte) {
 	s.connections.Range(func(key, value interface{}) bool {
 		if wsConn, ok := value.(*WSConnection); ok && wsConn.isAuth {
-			wsConn.conn.WriteMessage(websocket.TextMessage, message)
+			wsConn.writeMessage(message)
 		}
 		return true
 	})
@@ -21,10 +21,10 @@ func main() {
 
 	engine := gin.Default()
 
-	httpServer := server.NewHTTPServer(engine)
-
 	wsServer := server.NewWSServer()
 
+	httpServer := server.NewHTTPServer(engine, wsServer)
+
 	var g errgroup.Group
 
 	g.Go(func() error {
@@ -7,11 +7,15 @@ CREATE TABLE `users` (
   `avatar_url` varchar(255) DEFAULT NULL,
   `signature` varchar(255) DEFAULT NULL,
   `status` int DEFAULT NULL,
+  `id_changed_at` datetime DEFAULT NULL,
+  `delete_at` datetime DEFAULT NULL,
+  `role` enum('user','admin') NOT NULL DEFAULT 'user',
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`uid`),
   UNIQUE KEY `id` (`id`),
-  UNIQUE KEY `email` (`email`)
+  UNIQUE KEY `email` (`email`),
+  FULLTEXT KEY `ft_name` (`name`) WITH PARSER ngram
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 CREATE TABLE `chat_groups` (
@@ -22,6 +26,7 @@ CREATE TABLE `chat_groups` (
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   PRIMARY KEY (`gid`),
   KEY `owner_id` (`owner_id`),
+  FULLTEXT KEY `ft_name` (`name`) WITH PARSER ngram,
   CONSTRAINT `chat_groups_ibfk_1` FOREIGN KEY (`owner_id`) REFERENCES `users` (`uid`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
@@ -50,8 +55,17 @@ CREATE TABLE `comments` (
   `post_id` bigint NOT NULL,
   `user_id` char(36) NOT NULL,
   `content` text NOT NULL,
+  `parent_comment_id` bigint DEFAULT NULL,
+  `reply_to_user_id` char(36) DEFAULT NULL,
+  `like_count` int NOT NULL DEFAULT '0',
+  `reply_count` int NOT NULL DEFAULT '0',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  PRIMARY KEY (`comment_id`)
+  `edited_at` datetime DEFAULT NULL,
+  `deleted_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`comment_id`),
+  KEY `idx_post_id` (`post_id`),
+  KEY `idx_parent_created` (`parent_comment_id`,`created_at`),
+  FULLTEXT KEY `ft_content` (`content`) WITH PARSER ngram
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 CREATE TABLE `friend_requests` (
@@ -116,7 +130,9 @@ CREATE TABLE `group_message` (
   `type` varchar(16) NOT NULL,
   `content` text,
   `extra` json DEFAULT NULL,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  KEY `idx_group_timestamp` (`group_id`,`timestamp`),
+  FULLTEXT KEY `ft_content` (`content`) WITH PARSER ngram
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 CREATE TABLE `post_likes` (
@@ -132,9 +148,18 @@ CREATE TABLE `posts` (
   `title` text NOT NULL,
   `content` text NOT NULL,
   `image_url` varchar(255) DEFAULT NULL,
+  `visibility` enum('public','friends','private','custom') NOT NULL DEFAULT 'public',
+  `repost_of_id` bigint DEFAULT NULL,
+  `share_count` int NOT NULL DEFAULT '0',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` datetime DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
-  PRIMARY KEY (`post_id`)
+  `edited_at` datetime DEFAULT NULL,
+  `deleted_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`post_id`),
+  KEY `idx_created_post` (`created_at`,`post_id`),
+  KEY `idx_user_created` (`user_id`,`created_at`),
+  KEY `idx_repost_of_id` (`repost_of_id`),
+  FULLTEXT KEY `ft_title_content` (`title`,`content`) WITH PARSER ngram
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 CREATE TABLE `private_messages` (
@@ -145,5 +170,276 @@ CREATE TABLE `private_messages` (
   `type` varchar(16) NOT NULL,
   `content` text,
   `extra` json DEFAULT NULL,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  KEY `idx_sender_timestamp` (`sender_id`,`timestamp`),
+  KEY `idx_receiver_timestamp` (`receiver_id`,`timestamp`),
+  FULLTEXT KEY `ft_content` (`content`) WITH PARSER ngram
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `user_sessions` (
+  `session_id` char(36) NOT NULL,
+  `uid` char(36) NOT NULL,
+  `refresh_hash` char(64) NOT NULL,
+  `prev_refresh_hash` char(64) DEFAULT NULL,
+  `user_agent` varchar(255) DEFAULT NULL,
+  `ip` varchar(64) DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `last_used_at` datetime NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`session_id`),
+  UNIQUE KEY `refresh_hash` (`refresh_hash`),
+  KEY `prev_refresh_hash` (`prev_refresh_hash`),
+  KEY `uid` (`uid`),
+  CONSTRAINT `user_sessions_ibfk_1` FOREIGN KEY (`uid`) REFERENCES `users` (`uid`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `verification_codes` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `purpose` varchar(32) NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `ip` varchar(64) DEFAULT NULL,
+  `attempts` int NOT NULL DEFAULT '0',
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_purpose_email` (`purpose`,`email`),
+  KEY `idx_email_created` (`email`,`created_at`),
+  KEY `idx_ip_created` (`ip`,`created_at`),
+  KEY `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `login_history` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `uid` char(36) DEFAULT NULL,
+  `email` varchar(255) NOT NULL,
+  `ip` varchar(64) DEFAULT NULL,
+  `user_agent` varchar(255) DEFAULT NULL,
+  `success` tinyint(1) NOT NULL DEFAULT '0',
+  `reason` varchar(32) DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_uid_created` (`uid`,`created_at`),
+  CONSTRAINT `login_history_ibfk_1` FOREIGN KEY (`uid`) REFERENCES `users` (`uid`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `user_totp` (
+  `uid` char(36) NOT NULL,
+  `secret` varchar(64) NOT NULL,
+  `enabled` tinyint(1) NOT NULL DEFAULT '0',
+  `last_used_step` bigint NOT NULL DEFAULT '0',
+  `created_at` datetime NOT NULL,
+  `enabled_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`uid`),
+  CONSTRAINT `user_totp_ibfk_1` FOREIGN KEY (`uid`) REFERENCES `users` (`uid`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `user_recovery_codes` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `uid` char(36) NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_uid_code` (`uid`,`code_hash`),
+  CONSTRAINT `user_recovery_codes_ibfk_1` FOREIGN KEY (`uid`) REFERENCES `users` (`uid`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `user_privacy` (
+  `uid` char(36) NOT NULL,
+  `search_policy` enum('all','handle','none') NOT NULL DEFAULT 'all',
+  `friend_policy` enum('approval','auto_accept','disabled') NOT NULL DEFAULT 'approval',
+  `post_visibility` enum('everyone','friends','nobody') NOT NULL DEFAULT 'everyone',
+  `show_online_status` tinyint(1) NOT NULL DEFAULT '1',
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`uid`),
+  CONSTRAINT `user_privacy_ibfk_1` FOREIGN KEY (`uid`) REFERENCES `users` (`uid`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `post_images` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `post_id` bigint NOT NULL,
+  `position` int NOT NULL DEFAULT '0',
+  `url` varchar(255) NOT NULL,
+  `thumbnail_url` varchar(255) NOT NULL,
+  `width` int NOT NULL DEFAULT '0',
+  `height` int NOT NULL DEFAULT '0',
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_post_position` (`post_id`,`position`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `comment_likes` (
+  `comment_id` bigint NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `liked_at` datetime NOT NULL,
+  PRIMARY KEY (`comment_id`,`user_id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `post_visible_users` (
+  `post_id` bigint NOT NULL,
+  `user_id` char(36) NOT NULL,
+  PRIMARY KEY (`post_id`,`user_id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `tags` (
+  `tag_id` bigint NOT NULL AUTO_INCREMENT,
+  `name` varchar(32) NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`tag_id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `post_tags` (
+  `post_id` bigint NOT NULL,
+  `tag_id` bigint NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`post_id`,`tag_id`),
+  KEY `idx_tag_created` (`tag_id`,`created_at`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `mentions` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `source_type` enum('post','comment','group_message') NOT NULL,
+  `source_id` bigint NOT NULL,
+  `mentioned_uid` char(36) NOT NULL,
+  `from_uid` char(36) NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_source_user` (`source_type`,`source_id`,`mentioned_uid`),
+  KEY `idx_mentioned_created` (`mentioned_uid`,`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `favorite_folders` (
+  `folder_id` bigint NOT NULL AUTO_INCREMENT,
+  `uid` char(36) NOT NULL,
+  `name` varchar(20) NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`folder_id`),
+  UNIQUE KEY `uk_uid_name` (`uid`,`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `favorites` (
+  `favorite_id` bigint NOT NULL AUTO_INCREMENT,
+  `uid` char(36) NOT NULL,
+  `folder_id` bigint DEFAULT NULL,
+  `source_type` enum('post','private_message','group_message') NOT NULL,
+  `source_id` bigint NOT NULL,
+  `title` text,
+  `content` text,
+  `extra` json DEFAULT NULL,
+  `author_uid` char(36) NOT NULL,
+  `author_name` varchar(64) NOT NULL DEFAULT '',
+  `note` varchar(200) NOT NULL DEFAULT '',
+  `source_created_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`favorite_id`),
+  UNIQUE KEY `uk_uid_source` (`uid`,`source_type`,`source_id`),
+  KEY `idx_uid_folder_created` (`uid`,`folder_id`,`created_at`),
+  FULLTEXT KEY `ft_title_content_note` (`title`,`content`,`note`) WITH PARSER ngram
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `stories` (
+  `story_id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` char(36) NOT NULL,
+  `type` enum('image','text') NOT NULL,
+  `content` varchar(200) NOT NULL DEFAULT '',
+  `image_url` varchar(255) NOT NULL DEFAULT '',
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`story_id`),
+  KEY `idx_user_expires` (`user_id`,`expires_at`),
+  KEY `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `story_views` (
+  `story_id` bigint NOT NULL,
+  `viewer_uid` char(36) NOT NULL,
+  `viewed_at` datetime NOT NULL,
+  PRIMARY KEY (`story_id`,`viewer_uid`),
+  KEY `idx_viewer_uid` (`viewer_uid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `moderation_flags` (
+  `flag_id` bigint NOT NULL AUTO_INCREMENT,
+  `scene` varchar(20) NOT NULL,
+  `source_id` varchar(64) NOT NULL,
+  `uid` char(36) NOT NULL,
+  `content` text NOT NULL,
+  `hits` json NOT NULL,
+  `status` enum('pending','approved','rejected') NOT NULL DEFAULT 'pending',
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`flag_id`),
+  KEY `idx_status_created` (`status`,`created_at`),
+  KEY `idx_scene_source` (`scene`,`source_id`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `reports` (
+  `report_id` bigint NOT NULL AUTO_INCREMENT,
+  `reporter_uid` char(36) NOT NULL,
+  `target_type` enum('post','comment','private_message','group_message','user','group') NOT NULL,
+  `target_id` varchar(64) NOT NULL,
+  `target_uid` char(36) NOT NULL DEFAULT '',
+  `reason` varchar(20) NOT NULL,
+  `detail` varchar(200) NOT NULL DEFAULT '',
+  `content` text NOT NULL,
+  `status` enum('pending','resolved','dismissed') NOT NULL DEFAULT 'pending',
+  `action` varchar(20) NOT NULL DEFAULT '',
+  `handler_uid` char(36) DEFAULT NULL,
+  `handle_note` varchar(200) NOT NULL DEFAULT '',
+  `handled_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`report_id`),
+  KEY `idx_status_created` (`status`,`created_at`),
+  KEY `idx_target` (`target_type`,`target_id`,`status`),
+  KEY `idx_reporter_uid` (`reporter_uid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `user_warnings` (
+  `warning_id` bigint NOT NULL AUTO_INCREMENT,
+  `uid` char(36) NOT NULL,
+  `admin_uid` char(36) NOT NULL,
+  `report_id` bigint DEFAULT NULL,
+  `reason` varchar(200) NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`warning_id`),
+  KEY `idx_uid_created` (`uid`,`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `user_suspensions` (
+  `suspension_id` bigint NOT NULL AUTO_INCREMENT,
+  `uid` char(36) NOT NULL,
+  `admin_uid` char(36) NOT NULL,
+  `report_id` bigint DEFAULT NULL,
+  `reason` varchar(200) NOT NULL,
+  `read_only` tinyint(1) NOT NULL DEFAULT '0',
+  `expires_at` datetime DEFAULT NULL,
+  `lifted_at` datetime DEFAULT NULL,
+  `lifted_by` char(36) DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`suspension_id`),
+  KEY `idx_uid_created` (`uid`,`created_at`),
+  KEY `idx_uid_lifted_expires` (`uid`,`lifted_at`,`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `audit_logs` (
+  `log_id` bigint NOT NULL AUTO_INCREMENT,
+  `admin_uid` char(36) NOT NULL,
+  `action` varchar(30) NOT NULL,
+  `target_type` varchar(20) NOT NULL,
+  `target_id` varchar(64) NOT NULL,
+  `report_id` bigint DEFAULT NULL,
+  `detail` text NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`log_id`),
+  KEY `idx_admin_created` (`admin_uid`,`created_at`),
+  KEY `idx_target` (`target_type`,`target_id`),
+  KEY `idx_created_at` (`created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,122 @@
+package utils
+
+import "unicode"
+
+// WordMatch 文本中命中的一个词，Start、End 为 rune 下标，左闭右开
+type WordMatch struct {
+	Word  string
+	Start int
+	End   int
+}
+
+type acNode struct {
+	children map[rune]int
+	fail     int
+	outputs  []int // 以该节点结尾的词在 words 中的下标，包含 fail 链上的词
+}
+
+// WordMatcher 基于 Aho-Corasick 自动机的多模式匹配，忽略大小写，构建后只读，可并发使用
+type WordMatcher struct {
+	nodes []acNode
+	words []string
+	runes []int // 每个词的 rune 长度
+}
+
+// NewWordMatcher 用词表构建自动机，空词和重复词会被忽略
+func NewWordMatcher(words []string) *WordMatcher {
+	m := &WordMatcher{nodes: []acNode{{children: map[rune]int{}}}}
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		key := string(foldRunes(word))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		m.insert(word, []rune(key))
+	}
+	m.buildFailLinks()
+	return m
+}
+
+func (m *WordMatcher) insert(word string, key []rune) {
+	cur := 0
+	for _, r := range key {
+		next, ok := m.nodes[cur].children[r]
+		if !ok {
+			m.nodes = append(m.nodes, acNode{children: map[rune]int{}})
+			next = len(m.nodes) - 1
+			m.nodes[cur].children[r] = next
+		}
+		cur = next
+	}
+	m.nodes[cur].outputs = append(m.nodes[cur].outputs, len(m.words))
+	m.words = append(m.words, word)
+	m.runes = append(m.runes, len(key))
+}
+
+// buildFailLinks 按层次遍历设置失配指针，并把 fail 链上的输出合并到当前节点
+func (m *WordMatcher) buildFailLinks() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].children {
+			fail := m.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].children[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].children[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			m.nodes[child].outputs = append(m.nodes[child].outputs, m.nodes[m.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// FindAll 返回文本中所有命中的词，允许重叠
+func (m *WordMatcher) FindAll(text string) []WordMatch {
+	if m == nil || len(m.words) == 0 {
+		return nil
+	}
+	var matches []WordMatch
+	cur := 0
+	for i, r := range foldRunes(text) {
+		for cur != 0 {
+			if _, ok := m.nodes[cur].children[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if next, ok := m.nodes[cur].children[r]; ok {
+			cur = next
+		}
+		for _, idx := range m.nodes[cur].outputs {
+			matches = append(matches, WordMatch{Word: m.words[idx], Start: i + 1 - m.runes[idx], End: i + 1})
+		}
+	}
+	return matches
+}
+
+// Len 返回词表中的词数
+func (m *WordMatcher) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.words)
+}
+
+// foldRunes 转为小写，逐个 rune 转换保证下标与原文一致
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
@@ -4,7 +4,9 @@ import (
 	"crypto/tls"
 	"fmt"
 	"gopkg.in/gomail.v2"
+	"html"
 	"strings"
+	"time"
 )
 
 type EmailSender struct {
@@ -41,13 +43,64 @@ func (e *EmailSender) Send(to, subject, html string) error {
 	return d.DialAndSend(m)
 }
 
-func GetEmailTemplate(code string) string {
+// GetEmailTemplate 注册验证码邮件模板
+func GetEmailTemplate(code string, ttl time.Duration) string {
+	return renderCodeTemplate("邮箱验证码", "您正在注册 NetherLink 账号，请在注册页面输入以下验证码：", code, ttl)
+}
+
+// GetResetPasswordEmailTemplate 重置密码验证码邮件模板
+func GetResetPasswordEmailTemplate(code string, ttl time.Duration) string {
+	return renderCodeTemplate("重置密码", "您正在重置 NetherLink 账号的登录密码，请在对应页面输入以下验证码：", code, ttl)
+}
+
+// GetChangeEmailTemplate 更换绑定邮箱验证码邮件模板，发送到新邮箱
+func GetChangeEmailTemplate(code string, ttl time.Duration) string {
+	return renderCodeTemplate("更换绑定邮箱", "您正在将 NetherLink 账号的绑定邮箱更换为本邮箱，请在对应页面输入以下验证码：", code, ttl)
+}
+
+// GetDeleteAccountEmailTemplate 注销账号验证码邮件模板
+func GetDeleteAccountEmailTemplate(code string, ttl time.Duration) string {
+	return renderCodeTemplate("注销账号", "您正在申请注销 NetherLink 账号，注销后账号数据将被删除且无法恢复。如确认注销，请输入以下验证码：", code, ttl)
+}
+
+// GetLoginAlertEmailTemplate 登录失败次数过多时发给账号所有者的提醒邮件模板
+func GetLoginAlertEmailTemplate(ip string, failures int, at time.Time) string {
+	return renderTemplate("登录安全提醒", fmt.Sprintf(`
+      <p>尊敬的用户：</p>
+      <p>您好！您的 NetherLink 账号近期连续 %d 次登录失败，账号已被临时锁定。</p>
+      <p>最近一次尝试时间：%s<br>来源IP：%s</p>
+      <p class="note">⚠️ 如非本人操作，说明有人正在尝试登录您的账号，建议尽快修改密码并开启两步验证。</p>`,
+		failures, at.Format("2006-01-02 15:04:05"), html.EscapeString(ip)))
+}
+
+func renderCodeTemplate(title, intro, code string, ttl time.Duration) string {
 	if len(code) != 6 {
 		code = fmt.Sprintf("%06s", code)
 	}
+	return renderTemplate(title, fmt.Sprintf(`
+      <p>尊敬的用户：</p>
+      <p>您好！%s</p>
+      <div class="otp-box">%s</div>
+      <p class="note">⚠️ 此验证码有效期为 %s，请尽快使用。</p>
+      <p>如非本人操作，请忽略此邮件。</p>`, intro, generateBoxes(code), formatValidity(ttl)))
+}
+
+// formatValidity 将有效期格式化为邮件中展示的文字，如 3 分钟、1 小时
+func formatValidity(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%d 小时", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d 分钟", d/time.Minute)
+	default:
+		return fmt.Sprintf("%d 秒", int64(d.Round(time.Second)/time.Second))
+	}
+}
+
+func renderTemplate(title, body string) string {
 	return fmt.Sprintf(`<!DOCTYPE html>
 <html lang="zh-CN">
-<head><meta charset="utf-8"><title>邮箱验证码</title>
+<head><meta charset="utf-8"><title>%[1]s</title>
 <style>
   body { margin:0; padding:20px; background:#f4f4f7; font-family:"Microsoft YaHei",Arial,sans-serif; color:#333; }
   .container { max-width:600px; margin:0 auto; background:#fff; border-radius:8px; box-shadow:0 2px 6px rgba(0,0,0,0.1); }
@@ -72,18 +125,13 @@ func GetEmailTemplate(code string) string {
 </head>
 <body>
   <div class="container">
-    <div class="header"><h1>邮箱验证码</h1></div>
-    <div class="body">
-      <p>尊敬的用户：</p>
-      <p>您好！您正在进行邮箱验证，请在对应页面输入以下验证码：</p>
-      <div class="otp-box">%s</div>
-      <p class="note">⚠️ 此验证码有效期为 3 分钟，请尽快使用。</p>
-      <p>如非本人操作，请忽略此邮件。</p>
+    <div class="header"><h1>%[1]s</h1></div>
+    <div class="body">%[2]s
     </div>
     <div class="footer">此邮件由系统自动发送，请勿回复。</div>
   </div>
 </body>
-</html>`, generateBoxes(code))
+</html>`, title, body)
 }
 
 func generateBoxes(code string) string {